@@ -0,0 +1,76 @@
+package Common
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+type Artifact struct {
+	Path    string
+	Size    int64
+	Hash    string
+	ModTime time.Time
+}
+
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Checks that the .ex4 of the target exists and was written after since
+func VerifyArtifact(target string, since time.Time) (Artifact, error) {
+	artifact := Artifact{Path: ArtifactPath(target)}
+
+	stat, err := os.Stat(artifact.Path)
+	if err != nil {
+		return artifact, fmt.Errorf("No compiled output found at %s", artifact.Path)
+	}
+
+	artifact.Size = stat.Size()
+	artifact.ModTime = stat.ModTime()
+
+	// wine and some filesystems only keep whole seconds
+	if artifact.ModTime.Before(since.Truncate(time.Second)) {
+		return artifact, fmt.Errorf("%s was not updated by this build", artifact.Path)
+	}
+
+	artifact.Hash, err = hashFile(artifact.Path)
+	if err != nil {
+		return artifact, err
+	}
+
+	return artifact, nil
+}
+
+func humanSize(size int64) string {
+	switch {
+	case size >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(size)/(1<<20))
+	case size >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(size)/(1<<10))
+	}
+	return fmt.Sprintf("%d B", size)
+}
+
+func PrintArtifact(artifact Artifact) {
+	Logger.Info("Artifact",
+		"path", artifact.Path,
+		"size", humanSize(artifact.Size),
+		"sha256", artifact.Hash[:12],
+	)
+	fmt.Println()
+}
@@ -0,0 +1,152 @@
+package Common
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	catppuccin "github.com/catppuccin/go"
+	"github.com/charmbracelet/lipgloss"
+)
+
+const DefaultBaselineFile = "go-mql-baseline.json"
+
+// A known warning, the count is how many times it shows up in the file
+type BaselineWarning struct {
+	File    string `json:"file"`
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Count   int    `json:"count"`
+}
+
+type Baseline struct {
+	Warnings []BaselineWarning `json:"warnings"`
+}
+
+var spacesRe = regexp.MustCompile(`\s+`)
+
+// Matches warnings by file, code and message, lines move with unrelated edits
+func baselineKey(file string, code int, message string) string {
+	file = filepath.ToSlash(strings.TrimPrefix(file, "./"))
+	message = strings.ToLower(spacesRe.ReplaceAllString(strings.TrimSpace(message), " "))
+	return fmt.Sprintf("%s\x00%d\x00%s", file, code, message)
+}
+
+func LoadBaseline(path string) (Baseline, error) {
+	var baseline Baseline
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return baseline, fmt.Errorf("Failed to read the baseline: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &baseline); err != nil {
+		return baseline, fmt.Errorf("Failed to parse the baseline %s: %w", path, err)
+	}
+
+	return baseline, nil
+}
+
+// Adds the warnings of the diagnostics to the baseline
+func (b *Baseline) Add(diagnostics Diagnostic) {
+	index := map[string]int{}
+	for i, warning := range b.Warnings {
+		index[baselineKey(warning.File, warning.Code, warning.Message)] = i
+	}
+
+	for _, info := range diagnostics.info {
+		if info.Type != "warning" {
+			continue
+		}
+
+		key := baselineKey(info.ScriptName, info.Code, info.Message)
+		if i, ok := index[key]; ok {
+			b.Warnings[i].Count++
+			continue
+		}
+
+		index[key] = len(b.Warnings)
+		b.Warnings = append(b.Warnings, BaselineWarning{
+			File:    filepath.ToSlash(info.ScriptName),
+			Code:    info.Code,
+			Message: info.Message,
+			Count:   1,
+		})
+	}
+}
+
+func (b Baseline) Save(path string) error {
+	sort.SliceStable(b.Warnings, func(i, j int) bool {
+		if b.Warnings[i].File != b.Warnings[j].File {
+			return b.Warnings[i].File < b.Warnings[j].File
+		}
+		return b.Warnings[i].Code < b.Warnings[j].Code
+	})
+
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// Marks the warnings of the diagnostics the baseline knows of and returns the
+// ones it doesn't, a warning that shows up more often than the baseline knows
+// of counts as new too. It runs before the severity so --werror can't turn the
+// known warnings into errors.
+func (b Baseline) NewWarnings(diagnostics Diagnostic) (Diagnostic, []Info) {
+	known := map[string]int{}
+	for _, warning := range b.Warnings {
+		known[baselineKey(warning.File, warning.Code, warning.Message)] += warning.Count
+	}
+
+	infos := append([]Info(nil), diagnostics.info...)
+
+	var added []Info
+	for i, info := range infos {
+		if info.Type != "warning" {
+			continue
+		}
+
+		key := baselineKey(info.ScriptName, info.Code, info.Message)
+		if known[key] > 0 {
+			known[key]--
+			infos[i].Baselined = true
+			continue
+		}
+		added = append(added, info)
+	}
+
+	diagnostics.info = infos
+	return diagnostics, added
+}
+
+func PrintBaselineWarnings(added []Info, diagnostics Diagnostic, path string) {
+	known := 0
+	for _, info := range diagnostics.info {
+		if info.Baselined {
+			known++
+		}
+	}
+
+	fmt.Println()
+
+	if len(added) == 0 {
+		Logger.Info("No new warnings", "baseline", path, "known", known)
+		return
+	}
+
+	warnStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(catppuccin.Mocha.Yellow().Hex))
+
+	Logger.Error("New warnings", "baseline", path, "new", len(added), "known", known)
+	fmt.Println()
+	for _, info := range added {
+		location := fmt.Sprintf("%s:%d:%d", info.ScriptName, info.Line, info.Char)
+		fmt.Printf("  %s %s %s\n", warnStyle.Render(fmt.Sprintf("%d", info.Code)), FaintStyle.Render(location), info.Message)
+	}
+}
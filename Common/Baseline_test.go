@@ -0,0 +1,50 @@
+package Common
+
+import "testing"
+
+func TestBaselineWithWerror(t *testing.T) {
+	known := Info{ScriptName: "Experts/ea.mq4", Type: "warning", Line: 4, Char: 6, Code: 43, Message: "possible loss of data due to type conversion"}
+	moved := known
+	moved.Line = 12
+	added := Info{ScriptName: "Experts/ea.mq4", Type: "warning", Line: 20, Char: 3, Code: 83, Message: "return value of 'OrderSend' should be checked"}
+
+	baseline := Baseline{Warnings: []BaselineWarning{
+		{File: "./Experts/ea.mq4", Code: 43, Message: "Possible loss of data  due to type conversion", Count: 1},
+	}}
+
+	tests := []struct {
+		name         string
+		infos        []Info
+		werror       bool
+		wantNew      int
+		wantErrors   int
+		wantWarnings int
+	}{
+		{"known warning", []Info{known}, false, 0, 0, 1},
+		{"known warning with werror", []Info{known}, true, 0, 0, 1},
+		{"moved known warning with werror", []Info{moved}, true, 0, 0, 1},
+		{"new warning", []Info{known, added}, false, 1, 0, 2},
+		{"new warning with werror", []Info{known, added}, true, 1, 1, 1},
+		{"known warning twice with werror", []Info{known, moved}, true, 1, 1, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diagnostics := Diagnostic{info: tt.infos, totalWarnings: len(tt.infos)}
+
+			diagnostics, newWarnings := baseline.NewWarnings(diagnostics)
+			diagnostics = ApplySeverity(diagnostics, nil, tt.werror)
+
+			if len(newWarnings) != tt.wantNew {
+				t.Errorf("NewWarnings() = %d new warnings, want %d", len(newWarnings), tt.wantNew)
+			}
+			if diagnostics.totalErrors != tt.wantErrors || diagnostics.totalWarnings != tt.wantWarnings {
+				t.Errorf("got %d errors and %d warnings, want %d and %d",
+					diagnostics.totalErrors, diagnostics.totalWarnings, tt.wantErrors, tt.wantWarnings)
+			}
+			if diagnostics.HasErrors() != (tt.wantErrors > 0) {
+				t.Errorf("HasErrors() = %v, want %v", diagnostics.HasErrors(), tt.wantErrors > 0)
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package Common
+
+import (
+	_ "embed"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	ini "gopkg.in/ini.v1"
+)
+
+//go:embed catalog.ini
+var catalogData []byte
+
+// What a compiler message means and how to fix it
+type CatalogEntry struct {
+	Name    string
+	Codes   []int
+	Match   string
+	Explain string
+	Hint    string
+}
+
+var catalog []CatalogEntry
+
+func loadCatalog() []CatalogEntry {
+	if catalog != nil {
+		return catalog
+	}
+
+	// the explanations quote ';' and '#', they aren't comments
+	file, err := ini.LoadSources(ini.LoadOptions{IgnoreInlineComment: true}, catalogData)
+	if err != nil {
+		panic(err)
+	}
+
+	for _, section := range file.Sections() {
+		if section.Name() == ini.DefaultSection {
+			continue
+		}
+
+		entry := CatalogEntry{
+			Name:    section.Name(),
+			Match:   section.Key("match").String(),
+			Explain: section.Key("explain").String(),
+			Hint:    section.Key("hint").String(),
+		}
+		for _, code := range section.Key("codes").Strings(",") {
+			if number, err := strconv.Atoi(code); err == nil {
+				entry.Codes = append(entry.Codes, number)
+			}
+		}
+
+		catalog = append(catalog, entry)
+	}
+
+	return catalog
+}
+
+func (e CatalogEntry) hasCode(code int) bool {
+	for _, c := range e.Codes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// Finds the entry of a diagnostic, by its message first since the codes
+// change between metaeditor builds
+func LookupDiagnostic(info Info) (CatalogEntry, bool) {
+	message := strings.ToLower(info.Message)
+
+	for _, entry := range loadCatalog() {
+		if entry.Match != "" && strings.Contains(message, entry.Match) {
+			return entry, true
+		}
+	}
+
+	for _, entry := range loadCatalog() {
+		if entry.hasCode(info.Code) {
+			return entry, true
+		}
+	}
+
+	return CatalogEntry{}, false
+}
+
+// shorter parts of a message match half the catalog
+const minCatalogQuery = 4
+
+// the quoted name metaeditor puts before the message, 'OrderSend' - ...
+var messageNameRe = regexp.MustCompile(`^'[^']*'\s*-\s*`)
+
+// Finds the entries of a code, or the ones whose message contains the query
+func LookupCatalog(query string) ([]CatalogEntry, error) {
+	var entries []CatalogEntry
+
+	query = strings.TrimSpace(query)
+
+	if code, err := strconv.Atoi(query); err == nil {
+		for _, entry := range loadCatalog() {
+			if entry.hasCode(code) {
+				entries = append(entries, entry)
+			}
+		}
+		return entries, nil
+	}
+
+	// a message pasted from the log works too
+	query = strings.ToLower(messageNameRe.ReplaceAllString(query, ""))
+	if len([]rune(query)) < minCatalogQuery {
+		return nil, fmt.Errorf("%q is too short, give a code or at least %d letters of the message", query, minCatalogQuery)
+	}
+
+	for _, entry := range loadCatalog() {
+		if entry.Match != "" && strings.Contains(entry.Match, query) {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries, nil
+}
+
+// One line under the diagnostic with the fix
+func printHint(info Info) {
+	entry, ok := LookupDiagnostic(info)
+	if !ok {
+		return
+	}
+
+	if PlainOutput {
+		fmt.Printf("      = hint: %s\n", entry.Hint)
+		return
+	}
+
+	fmt.Println(FaintStyle.Render("  hint: " + entry.Hint))
+}
+
+func PrintCatalogEntry(entry CatalogEntry) {
+	var codes []string
+	for _, code := range entry.Codes {
+		codes = append(codes, strconv.Itoa(code))
+	}
+
+	title := entry.Name
+	if len(codes) > 0 {
+		title += " (" + strings.Join(codes, ", ") + ")"
+	}
+
+	fmt.Println()
+	fmt.Println(Bold.Render(title))
+	fmt.Println()
+	fmt.Println(entry.Explain)
+	fmt.Println()
+	fmt.Println(FaintStyle.Render("Fix: ") + entry.Hint)
+}
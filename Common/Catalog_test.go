@@ -0,0 +1,63 @@
+package Common
+
+import "testing"
+
+func TestLookupCatalog(t *testing.T) {
+	tests := []struct {
+		query   string
+		want    []string
+		wantErr bool
+	}{
+		{"150", []string{"function must return a value"}, false},
+		{"256", []string{"undeclared identifier"}, false},
+		{"999", nil, false},
+		{"function not defined", []string{"function not defined"}, false},
+		{"'OrderSend' - function not defined", []string{"function not defined"}, false},
+		{"Possible loss of data", []string{"possible loss of data"}, false},
+		{"return a value", []string{"not all control paths return a value", "function must return a value"}, false},
+		{"a", nil, true},
+		{"'x' - ab", nil, true},
+	}
+
+	for _, tt := range tests {
+		entries, err := LookupCatalog(tt.query)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("LookupCatalog(%q) error = %v, want error %v", tt.query, err, tt.wantErr)
+			continue
+		}
+
+		var names []string
+		for _, entry := range entries {
+			names = append(names, entry.Name)
+		}
+		if len(names) != len(tt.want) {
+			t.Errorf("LookupCatalog(%q) = %q, want %q", tt.query, names, tt.want)
+			continue
+		}
+		for i := range names {
+			if names[i] != tt.want[i] {
+				t.Errorf("LookupCatalog(%q) = %q, want %q", tt.query, names, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestLookupDiagnostic(t *testing.T) {
+	tests := []struct {
+		info Info
+		want string
+	}{
+		{Info{Code: 43, Message: "possible loss of data due to type conversion"}, "possible loss of data"},
+		// the message wins over a code of another build
+		{Info{Code: 256, Message: "'OrderSend' - function not defined"}, "function not defined"},
+		{Info{Code: 150, Message: "'return' - something new"}, "function must return a value"},
+	}
+
+	for _, tt := range tests {
+		entry, ok := LookupDiagnostic(tt.info)
+		if !ok || entry.Name != tt.want {
+			t.Errorf("LookupDiagnostic(%+v) = %q, %v, want %q", tt.info, entry.Name, ok, tt.want)
+		}
+	}
+}
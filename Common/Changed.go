@@ -0,0 +1,93 @@
+package Common
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+func isMQLSource(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".mq4" || ext == ".mqh"
+}
+
+// Runs git with -z output, so that paths with spaces and unicode stay intact
+func gitPaths(args ...string) ([]string, error) {
+	var stderr bytes.Buffer
+
+	cmd := exec.Command("git", args...)
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to run git %s: %s", strings.Join(args, " "), strings.TrimSpace(stderr.String()))
+	}
+
+	return strings.FieldsFunc(string(out), func(r rune) bool { return r == 0 }), nil
+}
+
+// The .mq4 and .mqh files of the cwd that differ from the ref, uncommitted
+// and untracked ones included
+func changedSources(ref string) ([]string, error) {
+	changed, err := gitPaths("diff", "--name-only", "--relative", "-z", ref, "--")
+	if err != nil {
+		return nil, err
+	}
+
+	untracked, err := gitPaths("ls-files", "--others", "--exclude-standard", "-z")
+	if err != nil {
+		return nil, err
+	}
+
+	var sources []string
+	for _, path := range append(changed, untracked...) {
+		// deleted files have nothing to compile
+		if _, err := os.Stat(path); err != nil || !isMQLSource(path) {
+			continue
+		}
+		sources = append(sources, filepath.Clean(path))
+	}
+
+	return sources, nil
+}
+
+// The targets of the cwd that changed since the ref or include a changed header
+func ChangedTargets(ref string) ([]string, error) {
+	sources, err := changedSources(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	changed := map[string]bool{}
+	for _, source := range sources {
+		changed[absPath(source)] = true
+	}
+
+	var targets []string
+	filepath.WalkDir(".", func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || !strings.EqualFold(filepath.Ext(path), ".mq4") {
+			return nil
+		}
+
+		// leftovers of an interrupted build with -D
+		if strings.HasSuffix(path, ".defines.mq4") {
+			return nil
+		}
+
+		for _, file := range collectIncludes(path) {
+			if changed[absPath(file)] {
+				targets = append(targets, path)
+				break
+			}
+		}
+		return nil
+	})
+
+	sort.Strings(targets)
+	return targets, nil
+}
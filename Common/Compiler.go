@@ -3,29 +3,20 @@ package Common
 import (
 	"fmt"
 	"os"
-	"os/exec"
-	"runtime"
 	"time"
-
-	"github.com/charmbracelet/huh/spinner"
-	"github.com/charmbracelet/lipgloss"
-	"golang.org/x/exp/rand"
 )
 
-func compileMetaEditor(target, logfile string, cfg *MQLConfig) (outputStr string, status int) {
-	// MT4 should be on portable mode
-
-	// for linux and mac
+func compileMetaEditor(target, logfile string, cfg *MQLConfig, timings *Timings) (outputStr string, status int) {
+	cmd := metaEditorCommand(cfg, target, logfile)
 
-	cmd := exec.Command("wine", cfg.MetaEditorPath, "/compile:"+target, "/log:"+logfile)
-
-	if runtime.GOOS == "windows" {
-		cmd = exec.Command(cfg.MetaEditorPath, "/compile:"+target, "/log:"+logfile)
-	}
+	start := time.Now()
 
 	// check the status of the command
 	cmd.Run()
 
+	timings.Run = time.Since(start)
+	start = time.Now()
+
 	// read the log file
 	logFile, err := os.ReadFile(logfile)
 	if err != nil {
@@ -37,36 +28,19 @@ func compileMetaEditor(target, logfile string, cfg *MQLConfig) (outputStr string
 		return "", 1
 	}
 
+	timings.Decode = time.Since(start)
+
 	return logFileUTF8, 0
 }
 
-func Compile(target string, logfile string, compileTarget map[string]string, cfg *MQLConfig) (outputStr string, status int) {
+func Compile(target string, logfile string, compileTarget map[string]string, cfg *MQLConfig, timings *Timings) (outputStr string, status int) {
 	fmt.Println()
 	Logger.Info("Compiling", Keyvals(compileTarget)...)
 	fmt.Println()
 
-	rand.Seed(uint64(time.Now().Nanosecond()))
-	randomSpinner := Spinners[rand.Intn(len(Spinners))]
-
-	runCompileCmd := func() {
-		outputStr, status = compileMetaEditor(target, logfile, cfg)
-	}
-	err := spinner.New().
-		Type(randomSpinner).
-		Title(SpinnerStyle.
-			Render(
-				fmt.Sprintf(
-					"Compiling %s",
-					SpinnerTitleStyle.Render(target),
-				),
-			),
-		).
-		Style(lipgloss.NewStyle().Foreground(lipgloss.Color("#F780E2")).PaddingLeft(1)).
-		Action(runCompileCmd).
-		Run()
-	if err != nil {
-		fmt.Println(err)
-	}
+	RunWithSpinner("Compiling", target, func() {
+		outputStr, status = compileMetaEditor(target, logfile, cfg, timings)
+	})
 
 	fmt.Println()
 
@@ -0,0 +1,114 @@
+package Common
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// The test binary doubles as metaeditor when GO_MQL_FAKE_METAEDITOR is set
+func TestMain(m *testing.M) {
+	if os.Getenv("GO_MQL_FAKE_METAEDITOR") == "1" {
+		os.Exit(fakeMetaEditor(os.Args[1:]))
+	}
+	os.Exit(m.Run())
+}
+
+// Compiles like metaeditor under wine: finds the source through its Z: path and
+// writes a UTF-16 log with a warning that quotes the path back
+func fakeMetaEditor(args []string) int {
+	var target, logfile string
+	for _, arg := range args {
+		if path, ok := strings.CutPrefix(arg, "/compile:"); ok {
+			target = path
+		}
+		if path, ok := strings.CutPrefix(arg, "/log:"); ok {
+			logfile = path
+		}
+	}
+
+	fromZ := func(path string) string {
+		return "/" + strings.ReplaceAll(strings.TrimPrefix(path, `Z:\`), `\`, "/")
+	}
+
+	if _, err := os.Stat(fromZ(target)); err != nil {
+		return 1
+	}
+
+	log := fmt.Sprintf(
+		"%s(4,6) : warning 43: possible loss of data due to type conversion\r\nResult: 0 errors, 1 warnings, 5 msec elapsed\r\n",
+		target,
+	)
+	if err := os.WriteFile(fromZ(logfile), append([]byte{0xff, 0xfe}, EncodeUTF16(log)...), 0o644); err != nil {
+		return 1
+	}
+	return 0
+}
+
+func TestCompileUnicodePaths(t *testing.T) {
+	dir := fakeWineDrives(t)
+
+	targets := []string{
+		filepath.Join(dir, "Experts", "MACD Sample.mq4"),
+		filepath.Join(dir, "Experts", "Мой советник", "советник.mq4"),
+		filepath.Join(dir, "Experts", "目标 策略", "ea 😀.mq4"),
+	}
+
+	cfg := &MQLConfig{
+		Wine:           os.Args[0],
+		WineEnv:        []string{"GO_MQL_FAKE_METAEDITOR=1"},
+		MetaEditorPath: "metaeditor.exe",
+	}
+
+	for _, target := range targets {
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(target, []byte("void OnTick() {}\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		logfile := strings.TrimSuffix(target, ".mq4") + ".log"
+
+		output, status := compileMetaEditor(target, logfile, cfg, &Timings{})
+		if status != 0 {
+			t.Fatalf("compileMetaEditor(%q) status = %d, the fake metaeditor didn't find the source", target, status)
+		}
+
+		diagnostics := ParseLogFile(output, "compile")
+		if len(diagnostics.info) != 1 {
+			t.Fatalf("ParseLogFile() found %d diagnostics in %q, want 1", len(diagnostics.info), output)
+		}
+
+		info := diagnostics.info[0]
+		if info.ScriptName != target || info.Line != 4 || info.Char != 6 || info.Code != 43 {
+			t.Errorf("diagnostic = %+v, want it at %s(4,6)", info, target)
+		}
+		if diagnostics.totalWarnings != 1 {
+			t.Errorf("totalWarnings = %d, want 1", diagnostics.totalWarnings)
+		}
+	}
+}
+
+func TestMetaEditorCommandUnderWine(t *testing.T) {
+	dir := fakeWineDrives(t)
+
+	target := filepath.Join(dir, "Experts", "Мой советник", "MACD Sample.mq4")
+	logfile := filepath.Join(dir, "build", "MACD Sample.log")
+
+	cmd := metaEditorCommand(&MQLConfig{Wine: "wine", MetaEditorPath: "metaeditor.exe"}, target, logfile, "/s")
+
+	want := []string{
+		"wine",
+		"metaeditor.exe",
+		`/compile:Z:` + strings.ReplaceAll(target, "/", `\`),
+		`/log:Z:` + strings.ReplaceAll(logfile, "/", `\`),
+		"/s",
+	}
+	if !reflect.DeepEqual(cmd.Args, want) {
+		t.Errorf("metaEditorCommand() args = %q, want %q", cmd.Args, want)
+	}
+}
@@ -0,0 +1,59 @@
+package Common
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var defineNameRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Turns NAME=value (or NAME) definitions into #define lines
+func DefineLines(defines []string) ([]string, error) {
+	var lines []string
+
+	for _, define := range defines {
+		name, value, _ := strings.Cut(define, "=")
+		name = strings.TrimSpace(name)
+
+		if !defineNameRe.MatchString(name) {
+			return nil, fmt.Errorf("Invalid define %q, expected NAME=value", define)
+		}
+
+		lines = append(lines, strings.TrimSpace("#define "+name+" "+value))
+	}
+
+	return lines, nil
+}
+
+// Writes a wrapper next to the target that defines the names and includes the
+// target, metaeditor has no command line defines. Returns the wrapper path
+func WriteDefinesWrapper(target string, defines []string) (string, error) {
+	lines, err := DefineLines(defines)
+	if err != nil {
+		return "", err
+	}
+
+	base := strings.TrimSuffix(filepath.Base(target), filepath.Ext(target))
+	wrapper := filepath.Join(filepath.Dir(target), base+".defines"+filepath.Ext(target))
+
+	lines = append(lines, fmt.Sprintf("#include \"%s\"", filepath.Base(target)))
+
+	err = os.WriteFile(wrapper, []byte(strings.Join(lines, "\r\n")+"\r\n"), 0o644)
+	if err != nil {
+		return "", err
+	}
+
+	return wrapper, nil
+}
+
+// Removes the wrapper and gives its .ex4 the name of the target
+func CleanupDefinesWrapper(target, wrapper string) {
+	os.Remove(wrapper)
+
+	if _, err := os.Stat(ArtifactPath(wrapper)); err == nil {
+		moveFile(ArtifactPath(wrapper), ArtifactPath(target))
+	}
+}
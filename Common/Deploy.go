@@ -0,0 +1,117 @@
+package Common
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var (
+	indicatorRe = regexp.MustCompile(`(?m)^\s*#property\s+indicator_(chart|separate)_window|\bOnCalculate\s*\(`)
+	scriptRe    = regexp.MustCompile(`(?m)^\s*#property\s+show_inputs|\bOnStart\s*\(`)
+)
+
+// Returns the MQL4 folder (Experts, Indicators or Scripts) a program belongs to
+func ProgramType(target string) string {
+	// trust the folder the source lives in first, the innermost one wins
+	dirs := strings.Split(filepath.ToSlash(filepath.Dir(target)), "/")
+	for i := len(dirs) - 1; i >= 0; i-- {
+		switch strings.ToLower(dirs[i]) {
+		case "experts":
+			return "Experts"
+		case "indicators":
+			return "Indicators"
+		case "scripts":
+			return "Scripts"
+		}
+	}
+
+	source, err := os.ReadFile(target)
+	if err != nil {
+		return "Experts"
+	}
+
+	switch {
+	case indicatorRe.Match(source):
+		return "Indicators"
+	case scriptRe.Match(source):
+		return "Scripts"
+	}
+
+	return "Experts"
+}
+
+// The .ex4 metaeditor writes next to the source
+func ArtifactPath(target string) string {
+	return strings.TrimSuffix(target, filepath.Ext(target)) + ".ex4"
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+
+	return out.Close()
+}
+
+// Copies the compiled .ex4 (and the source if asked) into every configured
+// terminal, a failed copy doesn't stop the others
+func Deploy(target string, artifact Artifact, cfg *MQLConfig) error {
+	if len(cfg.DeployTo) == 0 {
+		return fmt.Errorf("No terminal folders to deploy to, set --deploy-to or $MQL4_DEPLOY_PATHS")
+	}
+
+	programType := ProgramType(target)
+
+	files := []string{artifact.Path}
+	if cfg.DeploySources {
+		files = append(files, target)
+	}
+
+	failed := 0
+
+	fmt.Println()
+	for _, dataFolder := range cfg.DeployTo {
+		// accept both the data folder and its MQL4 folder
+		mql4Folder := dataFolder
+		if !strings.EqualFold(filepath.Base(dataFolder), "MQL4") {
+			mql4Folder = filepath.Join(dataFolder, "MQL4")
+		}
+
+		destFolder := filepath.Join(mql4Folder, programType)
+
+		for _, file := range files {
+			dest := filepath.Join(destFolder, filepath.Base(file))
+			if err := copyFile(file, dest); err != nil {
+				Logger.Error("Deploy failed", "file", filepath.Base(file), "err", err)
+				failed++
+				continue
+			}
+			Logger.Info("Deployed", "file", filepath.Base(file), "to", destFolder)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d copies failed to deploy", failed, len(files)*len(cfg.DeployTo))
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package Common
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestProgramType(t *testing.T) {
+	tests := []struct {
+		target string
+		want   string
+	}{
+		{"Experts/ea.mq4", "Experts"},
+		{"Indicators/Examples/signal.mq4", "Indicators"},
+		{"/home/me/Scripts/terminal/MQL4/Experts/ea.mq4", "Experts"},
+		{"/home/me/Experts/MQL4/Indicators/signal.mq4", "Indicators"},
+	}
+
+	for _, tt := range tests {
+		if got := ProgramType(tt.target); got != tt.want {
+			t.Errorf("ProgramType(%q) = %q, want %q", tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestDeployFailedCopy(t *testing.T) {
+	dir := t.TempDir()
+
+	artifact := filepath.Join(dir, "ea.ex4")
+	if err := os.WriteFile(artifact, []byte("EX4"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// a file where the terminal folder should be
+	notAFolder := filepath.Join(dir, "terminal")
+	if err := os.WriteFile(notAFolder, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &MQLConfig{DeployTo: []string{notAFolder}}
+	if err := Deploy("Experts/ea.mq4", Artifact{Path: artifact}, cfg); err == nil {
+		t.Error("Deploy() into a file succeeded, want an error")
+	}
+}
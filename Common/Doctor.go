@@ -0,0 +1,309 @@
+package Common
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	catppuccin "github.com/catppuccin/go"
+	"github.com/charmbracelet/lipgloss"
+	ini "gopkg.in/ini.v1"
+)
+
+// One line of the doctor checklist
+type Check struct {
+	Name   string
+	Passed bool
+	Detail string
+	// how to fix it when it failed
+	Hint string
+}
+
+// Runs the command, killing it if it takes longer than the timeout
+func runWithTimeout(cmd *exec.Cmd, timeout time.Duration) (string, error) {
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+
+	if err := cmd.Start(); err != nil {
+		return "", err
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	select {
+	case err := <-done:
+		return out.String(), err
+	case <-time.After(timeout):
+		cmd.Process.Kill()
+		return out.String(), fmt.Errorf("timed out after %s", timeout)
+	}
+}
+
+func checkWinePrefix(cfg *MQLConfig) Check {
+	check := Check{Name: "Wine prefix"}
+
+	if runtime.GOOS == "windows" {
+		check.Passed = true
+		check.Detail = "not needed on windows"
+		return check
+	}
+
+	prefix := winePrefix(cfg)
+
+	if _, err := os.Stat(filepath.Join(prefix, "system.reg")); err != nil {
+		check.Detail = prefix + " is not an initialised prefix"
+		check.Hint = fmt.Sprintf("run WINEPREFIX=%s wineboot, or point --wine-prefix to an existing one", prefix)
+		return check
+	}
+
+	// the prefix has to start too, a broken or foreign-arch prefix fails here
+	out, err := runWithTimeout(wineCommand(cfg, "cmd", "/c", "echo", "go-mql-ok"), time.Minute)
+	if err != nil || !strings.Contains(out, "go-mql-ok") {
+		check.Detail = fmt.Sprintf("%s doesn't start with %q", prefix, cfg.Wine)
+		if err != nil {
+			check.Detail += ": " + err.Error()
+		}
+		check.Hint = "check that --wine matches the prefix architecture (wine64 for 64 bit prefixes)"
+		return check
+	}
+
+	check.Passed = true
+	check.Detail = prefix
+	return check
+}
+
+func checkWine(cfg *MQLConfig) Check {
+	check := Check{Name: "Wine"}
+
+	if runtime.GOOS == "windows" {
+		check.Passed = true
+		check.Detail = "not needed on windows"
+		return check
+	}
+
+	wine := strings.Fields(cfg.Wine)
+	if len(wine) == 0 {
+		wine = []string{"wine"}
+	}
+
+	path, err := exec.LookPath(wine[0])
+	if err != nil {
+		check.Detail = wine[0] + " not found in $PATH"
+		check.Hint = "install wine from your package manager or set --wine / $MQL4_WINE"
+		return check
+	}
+
+	check.Passed = true
+	check.Detail = path
+
+	// wrappers like proton don't know --version, that's fine
+	out, err := runWithTimeout(exec.Command(wine[0], "--version"), 10*time.Second)
+	if version := strings.TrimSpace(out); err == nil && version != "" {
+		check.Detail += " (" + strings.Split(version, "\n")[0] + ")"
+	}
+
+	return check
+}
+
+func checkMetaEditor(cfg *MQLConfig) Check {
+	check := Check{Name: "MetaEditor"}
+
+	path, _ := filepath.Abs(cfg.MetaEditorPath)
+
+	stat, err := os.Stat(cfg.MetaEditorPath)
+	if err != nil || stat.IsDir() {
+		check.Detail = path + " not found"
+		check.Hint = "set --meta-editor or $MQL4_METAEDITOR_PATH to your metaeditor.exe"
+		return check
+	}
+
+	check.Passed = true
+	check.Detail = path
+	return check
+}
+
+func checkLogsWritable(cfg *MQLConfig) Check {
+	check := Check{Name: "Log folder"}
+
+	dir := filepath.Dir(OutputPath("doctor.mq4", ".log", cfg))
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		check.Detail = err.Error()
+		check.Hint = "check the permissions of --out-dir"
+		return check
+	}
+
+	file, err := os.CreateTemp(dir, ".go-mql-doctor-*")
+	if err != nil {
+		check.Detail = dir + " is not writable"
+		check.Hint = "check the permissions of the folder or pass a writable --out-dir"
+		return check
+	}
+	file.Close()
+	os.Remove(file.Name())
+
+	check.Passed = true
+	check.Detail, _ = filepath.Abs(dir)
+	return check
+}
+
+func checkTerminalIni() Check {
+	check := Check{Name: "terminal.ini"}
+
+	cfg, err := ini.Load(TerminalIniPath)
+	if err != nil {
+		check.Detail = TerminalIniPath + " not found"
+		check.Hint = "run the terminal once in portable mode and run go-mql-build from its MQL4 folder"
+		return check
+	}
+
+	check.Passed = true
+	check.Detail = "broker " + cfg.Section("Settings").Key("LastScanServer").String()
+	return check
+}
+
+func checkMQL4Layout() Check {
+	check := Check{Name: "MQL4 folder"}
+
+	cwd, _ := os.Getwd()
+
+	if !strings.EqualFold(filepath.Base(cwd), "MQL4") {
+		check.Detail = cwd + " is not an MQL4 folder"
+		check.Hint = "cd into the MQL4 folder of the terminal"
+		return check
+	}
+
+	var missing []string
+	for _, folder := range []string{"Experts", "Include", "Indicators", "Scripts"} {
+		if stat, err := os.Stat(folder); err != nil || !stat.IsDir() {
+			missing = append(missing, folder)
+		}
+	}
+
+	if len(missing) > 0 {
+		check.Detail = "missing " + strings.Join(missing, ", ")
+		check.Hint = "start the terminal once so it creates its MQL4 folders"
+		return check
+	}
+
+	check.Passed = true
+	check.Detail = cwd
+	return check
+}
+
+const doctorScript = "#property strict\r\nvoid OnStart()\r\n  {\r\n  }\r\n"
+
+func checkTestCompile(cfg *MQLConfig, canCompile bool) Check {
+	check := Check{Name: "Test compile"}
+
+	if !canCompile {
+		check.Detail = "skipped, fix the checks above first"
+		return check
+	}
+
+	dir, err := os.MkdirTemp("", "go-mql-doctor")
+	if err != nil {
+		check.Detail = err.Error()
+		return check
+	}
+	defer os.RemoveAll(dir)
+
+	target := filepath.Join(dir, "doctor.mq4")
+	logfile := filepath.Join(dir, "doctor.log")
+
+	if err := os.WriteFile(target, []byte(doctorScript), 0o644); err != nil {
+		check.Detail = err.Error()
+		return check
+	}
+
+	start := time.Now()
+
+	var outputStr string
+	var status int
+	RunWithSpinner("Test compiling", target, func() {
+		outputStr, status = compileMetaEditor(target, logfile, cfg, &Timings{})
+	})
+
+	if status != 0 {
+		check.Detail = "metaeditor wrote no log"
+		check.Hint = "run metaeditor by hand through wine and check its output"
+		return check
+	}
+
+	if !strings.Contains(outputStr, " 0 errors") {
+		check.Detail = "a trivial script failed to compile"
+		check.Hint = "compile a script with --logs and check the metaeditor log"
+		return check
+	}
+
+	if _, err := VerifyArtifact(target, start); err != nil {
+		check.Detail = err.Error()
+		check.Hint = "check that the wine drives map the temp folder (--wine-drive)"
+		return check
+	}
+
+	check.Passed = true
+	check.Detail = fmt.Sprintf("compiled in %s", time.Since(start).Round(time.Millisecond))
+	return check
+}
+
+func PrintChecks(checks []Check) {
+	passStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(catppuccin.Mocha.Green().Hex))
+	failStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(catppuccin.Mocha.Red().Hex))
+
+	fmt.Println()
+	for _, check := range checks {
+		mark := passStyle.Render("✓")
+		if PlainOutput {
+			mark = "[ok]  "
+		}
+
+		if !check.Passed {
+			mark = failStyle.Render("✗")
+			if PlainOutput {
+				mark = "[fail]"
+			}
+		}
+
+		fmt.Printf("%s %s %s\n", mark, Bold.Render(fmt.Sprintf("%-22s", check.Name)), FaintStyle.Render(check.Detail))
+
+		if !check.Passed && check.Hint != "" {
+			if PlainOutput {
+				fmt.Printf("       hint: %s\n", check.Hint)
+			} else {
+				fmt.Printf("  %s %s\n", failStyle.Render("╰─➤"), check.Hint)
+			}
+		}
+	}
+	fmt.Println()
+}
+
+// Prints the environment checklist and returns whether everything passed
+func RunDoctor(cfg *MQLConfig) bool {
+	checks := []Check{
+		checkWine(cfg),
+		checkWinePrefix(cfg),
+		checkMetaEditor(cfg),
+		checkLogsWritable(cfg),
+		checkTerminalIni(),
+		checkMQL4Layout(),
+	}
+
+	canCompile := checks[0].Passed && checks[1].Passed && checks[2].Passed
+	checks = append(checks, checkTestCompile(cfg, canCompile))
+
+	PrintChecks(checks)
+
+	for _, check := range checks {
+		if !check.Passed {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,354 @@
+package Common
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	catppuccin "github.com/catppuccin/go"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// A suggested edit of a source line, the runes [Start, End) become Text
+type Fix struct {
+	File   string
+	Line   int
+	Start  int
+	End    int
+	Text   string
+	Reason string
+}
+
+var undeclaredRe = regexp.MustCompile(`^'([A-Za-z_]\w*)' - undeclared identifier`)
+
+var conversionRe = regexp.MustCompile(`implicit conversion from '\w+' to '(\w+)'`)
+
+var valueTypes = `bool|char|uchar|short|ushort|int|uint|long|ulong|float|double|datetime|color|string`
+
+// `type name = value;` and `name = value;`, the value is the last group
+var declarationInitRe = regexp.MustCompile(`^(\s*(?:(?:static|const|input|extern)\s+)*(` + valueTypes + `)\s+[A-Za-z_]\w*\s*=\s*)([^=;].*?)\s*;`)
+var assignmentRe = regexp.MustCompile(`^(\s*[A-Za-z_][\w.]*(?:\[[^\]]*\])?\s*=\s*)([^=;].*?)\s*;`)
+
+var simpleExpressionRe = regexp.MustCompile(`^[\w.]+$`)
+
+func runeIndex(s string, byteIndex int) int {
+	return utf8.RuneCountInString(s[:byteIndex])
+}
+
+// Where the code of the line ends, before a // comment and trailing spaces
+func codeEnd(line string) int {
+	inString := false
+	for i := 0; i < len(line); i++ {
+		switch {
+		case line[i] == '"' && (i == 0 || line[i-1] != '\\'):
+			inString = !inString
+		case !inString && strings.HasPrefix(line[i:], "//"):
+			line = line[:i]
+		}
+	}
+	return runeIndex(line, len(strings.TrimRight(line, " \t\r")))
+}
+
+// Replaces the name nearest to the char the diagnostic points at
+func fixUndeclared(info Info, line string, symbols func() []string) (Fix, bool) {
+	match := undeclaredRe.FindStringSubmatch(info.Message)
+	if match == nil {
+		return Fix{}, false
+	}
+	name := match[1]
+
+	suggestion, ok := closestSymbol(name, symbols())
+	if !ok {
+		return Fix{}, false
+	}
+
+	nameRe := regexp.MustCompile(`\b` + regexp.QuoteMeta(name) + `\b`)
+
+	start := -1
+	for _, loc := range nameRe.FindAllStringIndex(line, -1) {
+		at := runeIndex(line, loc[0])
+		if start < 0 || abs(at-(info.Char-1)) < abs(start-(info.Char-1)) {
+			start = at
+		}
+	}
+	if start < 0 {
+		return Fix{}, false
+	}
+
+	return Fix{
+		Line:   info.Line,
+		Start:  start,
+		End:    start + utf8.RuneCountInString(name),
+		Text:   suggestion,
+		Reason: fmt.Sprintf("'%s' is undeclared, did you mean '%s'?", name, suggestion),
+	}, true
+}
+
+// Adds the ';' metaeditor misses, at the end of the statement before the char
+func fixSemicolon(info Info, lines []string) (Fix, bool) {
+	if !strings.Contains(info.Message, "semicolon expected") {
+		return Fix{}, false
+	}
+
+	runes := []rune(strings.TrimRight(lines[info.Line-1], "\r"))
+	char := min(max(info.Char-1, 0), len(runes))
+
+	// the statement ends on the line of the char
+	if before := string(runes[:char]); strings.TrimSpace(before) != "" {
+		if isPreprocessorLine(lines, info.Line-1) {
+			return Fix{}, false
+		}
+		at := utf8.RuneCountInString(strings.TrimRight(before, " \t"))
+		return Fix{Line: info.Line, Start: at, End: at, Text: ";", Reason: "missing ';'"}, true
+	}
+
+	// or on the last line of code above it, unless that one is complete
+	for i := info.Line - 2; i >= 0; i-- {
+		at := codeEnd(lines[i])
+		if at == 0 {
+			continue
+		}
+
+		// a ';' would end up in a directive or a macro
+		if isPreprocessorLine(lines, i) {
+			return Fix{}, false
+		}
+
+		if last := []rune(lines[i])[at-1]; last == ';' || last == '{' || last == '}' {
+			return Fix{}, false
+		}
+		return Fix{Line: i + 1, Start: at, End: at, Text: ";", Reason: "missing ';'"}, true
+	}
+
+	return Fix{}, false
+}
+
+// Whether the line is a directive or the continuation of a multi-line one
+func isPreprocessorLine(lines []string, i int) bool {
+	continues := func(line string) bool {
+		return strings.HasSuffix(strings.TrimRight(line, " \t\r"), "\\")
+	}
+
+	if strings.HasPrefix(strings.TrimSpace(lines[i]), "#") || continues(lines[i]) {
+		return true
+	}
+
+	for i--; i >= 0 && continues(lines[i]); i-- {
+		if strings.HasPrefix(strings.TrimSpace(lines[i]), "#") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Only the sources of the workspace are fixed, never the system headers
+func insideWorkspace(path string) bool {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return false
+	}
+
+	rel, err := filepath.Rel(cwd, absPath(path))
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// Makes the conversion explicit with a cast of the assigned value
+func fixConversion(info Info, line string) (Fix, bool) {
+	var castType string
+	var match []int
+
+	if conversion := conversionRe.FindStringSubmatch(info.Message); conversion != nil {
+		castType = conversion[1]
+		match = assignmentRe.FindStringSubmatchIndex(line)
+		if declaration := declarationInitRe.FindStringSubmatchIndex(line); declaration != nil {
+			match = []int{declaration[0], declaration[1], declaration[2], declaration[3], declaration[6], declaration[7]}
+		}
+	} else if strings.Contains(info.Message, "possible loss of data") {
+		declaration := declarationInitRe.FindStringSubmatchIndex(line)
+		if declaration == nil {
+			return Fix{}, false
+		}
+		castType = line[declaration[4]:declaration[5]]
+		match = []int{declaration[0], declaration[1], declaration[2], declaration[3], declaration[6], declaration[7]}
+	} else {
+		return Fix{}, false
+	}
+
+	if match == nil {
+		return Fix{}, false
+	}
+
+	value := line[match[4]:match[5]]
+	if strings.HasPrefix(value, "("+castType+")") {
+		return Fix{}, false
+	}
+
+	cast := "(" + castType + ")" + value
+	if !simpleExpressionRe.MatchString(value) {
+		cast = "(" + castType + ")(" + value + ")"
+	}
+
+	start := runeIndex(line, match[4])
+	return Fix{
+		Line:   info.Line,
+		Start:  start,
+		End:    start + utf8.RuneCountInString(value),
+		Text:   cast,
+		Reason: "explicit conversion to " + castType,
+	}, true
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// Suggests fixes for the diagnostics it knows how to fix
+func SuggestFixes(target string, diagnostics Diagnostic) []Fix {
+	var fixes []Fix
+
+	sources := map[string][]string{}
+
+	var symbols []string
+	lazySymbols := func() []string {
+		if symbols == nil {
+			symbols = symbolIndex(target)
+		}
+		return symbols
+	}
+
+	seen := map[string]bool{}
+	for _, info := range diagnostics.info {
+		if (info.Type != "error" && info.Type != "warning") || !insideWorkspace(info.ScriptName) {
+			continue
+		}
+
+		lines, ok := sources[info.ScriptName]
+		if !ok {
+			if source, _, err := ReadSource(info.ScriptName); err == nil {
+				lines = strings.Split(string(source), "\n")
+			}
+			sources[info.ScriptName] = lines
+		}
+		if info.Line < 1 || info.Line > len(lines) {
+			continue
+		}
+		line := strings.TrimRight(lines[info.Line-1], "\r")
+
+		fix, ok := fixUndeclared(info, line, lazySymbols)
+		if !ok {
+			fix, ok = fixSemicolon(info, lines)
+		}
+		if !ok {
+			fix, ok = fixConversion(info, line)
+		}
+		if !ok {
+			continue
+		}
+
+		fix.File = info.ScriptName
+
+		key := fmt.Sprintf("%s:%d:%d", fix.File, fix.Line, fix.Start)
+		if !seen[key] {
+			seen[key] = true
+			fixes = append(fixes, fix)
+		}
+	}
+
+	return fixes
+}
+
+func applyToLine(line string, fix Fix) string {
+	runes := []rune(line)
+	return string(runes[:fix.Start]) + fix.Text + string(runes[fix.End:])
+}
+
+// Shows the fixes as a diff of the lines they change
+func PrintFixes(fixes []Fix) {
+	if len(fixes) == 0 {
+		return
+	}
+
+	removed := lipgloss.NewStyle().Foreground(lipgloss.Color(catppuccin.Mocha.Red().Hex))
+	added := lipgloss.NewStyle().Foreground(lipgloss.Color(catppuccin.Mocha.Green().Hex))
+
+	Logger.Info("Suggested fixes", "Total", len(fixes), "apply", "--fix")
+	fmt.Println()
+
+	sources := map[string][]string{}
+	for _, fix := range fixes {
+		lines, ok := sources[fix.File]
+		if !ok {
+			source, _, err := ReadSource(fix.File)
+			if err != nil {
+				continue
+			}
+			lines = strings.Split(string(source), "\n")
+			sources[fix.File] = lines
+		}
+
+		line := strings.TrimRight(lines[fix.Line-1], "\r")
+
+		fmt.Println(Bold.Render(fmt.Sprintf("%s:%d", fix.File, fix.Line)) + " " + FaintStyle.Render(fix.Reason))
+		fmt.Println(removed.Render("- " + expandTabs(line)))
+		fmt.Println(added.Render("+ " + expandTabs(applyToLine(line, fix))))
+		fmt.Println()
+	}
+}
+
+// Writes the fixes into the sources of the workspace, fixes that overlap another
+// one are skipped
+func ApplyFixes(fixes []Fix) (applied int, err error) {
+	byFile := map[string][]Fix{}
+	var files []string
+	for _, fix := range fixes {
+		if !insideWorkspace(fix.File) {
+			continue
+		}
+
+		if _, ok := byFile[fix.File]; !ok {
+			files = append(files, fix.File)
+		}
+		byFile[fix.File] = append(byFile[fix.File], fix)
+	}
+
+	for _, file := range files {
+		source, isUTF16, err := ReadSource(file)
+		if err != nil {
+			return applied, err
+		}
+		lines := strings.Split(string(source), "\n")
+
+		// right to left so the earlier columns stay valid
+		fileFixes := byFile[file]
+		sort.Slice(fileFixes, func(i, j int) bool {
+			if fileFixes[i].Line != fileFixes[j].Line {
+				return fileFixes[i].Line < fileFixes[j].Line
+			}
+			return fileFixes[i].Start > fileFixes[j].Start
+		})
+
+		lastLine, lastStart := 0, 0
+		for _, fix := range fileFixes {
+			if fix.Line == lastLine && fix.End > lastStart {
+				continue
+			}
+			lines[fix.Line-1] = applyToLine(lines[fix.Line-1], fix)
+			lastLine, lastStart = fix.Line, fix.Start
+			applied++
+		}
+
+		if err := WriteSource(file, []byte(strings.Join(lines, "\n")), isUTF16); err != nil {
+			return applied, err
+		}
+	}
+
+	return applied, nil
+}
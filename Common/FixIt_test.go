@@ -0,0 +1,110 @@
+package Common
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFixSemicolon(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		line   int
+		char   int
+		want   Fix
+		wantOk bool
+	}{
+		{
+			name:   "previous line",
+			source: "void f()\n{\n   int a = 1\n   a++;\n}",
+			line:   4,
+			char:   4,
+			want:   Fix{Line: 3, Start: 12, End: 12, Text: ";"},
+			wantOk: true,
+		},
+		{
+			name:   "same line",
+			source: "int a = 1 int b = 2;",
+			line:   1,
+			char:   11,
+			want:   Fix{Line: 1, Start: 9, End: 9, Text: ";"},
+			wantOk: true,
+		},
+		{
+			name:   "directive",
+			source: "#define LOTS 0.1\nint a = 1;",
+			line:   2,
+			char:   1,
+			wantOk: false,
+		},
+		{
+			name:   "multi-line macro",
+			source: "#define CHECK(x) \\\n   if(!(x)) \\\n      Print(\"failed\")\nint a = 1;",
+			line:   4,
+			char:   1,
+			wantOk: false,
+		},
+		{
+			name:   "complete statement",
+			source: "int a = 1;\nint b = 2",
+			line:   2,
+			char:   1,
+			wantOk: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := Info{Line: tt.line, Char: tt.char, Message: "semicolon expected"}
+			fix, ok := fixSemicolon(info, strings.Split(tt.source, "\n"))
+			if ok != tt.wantOk {
+				t.Fatalf("fixSemicolon() ok = %v, want %v (%+v)", ok, tt.wantOk, fix)
+			}
+			if ok {
+				fix.Reason = ""
+				if fix != tt.want {
+					t.Errorf("fixSemicolon() = %+v, want %+v", fix, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestApplyFixesStaysInTheWorkspace(t *testing.T) {
+	chdirTemp(t)
+
+	outside := filepath.Join(t.TempDir(), "stdlib.mqh")
+	inside := filepath.Join("Experts", "ea.mq4")
+
+	for _, path := range []string{outside, inside} {
+		if err := os.WriteFile(path, []byte("int a = 1\n"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	fixes := []Fix{
+		{File: outside, Line: 1, Start: 9, End: 9, Text: ";"},
+		{File: inside, Line: 1, Start: 9, End: 9, Text: ";"},
+	}
+
+	applied, err := ApplyFixes(fixes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if applied != 1 {
+		t.Errorf("ApplyFixes() applied %d fixes, want 1", applied)
+	}
+
+	if source, _ := os.ReadFile(outside); string(source) != "int a = 1\n" {
+		t.Errorf("ApplyFixes() changed the file outside the workspace: %q", source)
+	}
+	if source, _ := os.ReadFile(inside); string(source) != "int a = 1;\n" {
+		t.Errorf("ApplyFixes() = %q, want the ';' added", source)
+	}
+
+	if info, err := os.Stat(inside); err != nil || info.Mode().Perm() != 0o755 {
+		t.Errorf("ApplyFixes() changed the mode of %s: %v %v", inside, info.Mode(), err)
+	}
+}
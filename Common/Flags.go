@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"runtime/debug"
+	"strings"
 
 	catppuccin "github.com/catppuccin/go"
 	"github.com/charmbracelet/lipgloss"
@@ -14,16 +15,66 @@ import (
 var VERSION = "unknown (built from source)"
 
 type MQLConfig struct {
+	// subcommand (package, ...) and its positional arguments
+	Command string
+	Args    []string
+
 	Compile        string
 	Syntax         string
 	MetaEditorPath string
 	Version        bool
 	Help           bool
 	PreserveLogs   bool
+	OutDir         string
+	Defines        []string
+	Context        int
+	WineDrives     []string
+	Wine           string
+	WinePrefix     string
+	WineDebug      string
+	WineEnv        []string
+	WineServer     bool
+	Timings        bool
+	Baseline       string
+	Werror         bool
+	Severity       string
+	Fix            bool
+	Changed        string
+
+	Test         bool
+	TerminalPath string
+	TesterSymbol string
+	TesterPeriod string
+	TesterModel  int
+	TesterFrom   string
+	TesterTo     string
+	TesterSet    string
+	TesterReport string
+
+	Deploy        bool
+	DeployTo      []string
+	DeploySources bool
+
+	PackageName    string
+	PackageVersion string
+
+	Description string
+	BuildID     bool
 }
 
 var MqlConfig *MQLConfig
 
+// subcommands listed in the help menu
+var Commands = [][2]string{
+	{"package <targets>...", "Compiles the targets and bundles them into a versioned zip"},
+	{"version <target> [bump]", "Prints or bumps (major, minor, patch or x.yy) the #property version"},
+	{"matrix [matrix.ini]", "Builds every target × variant × metaeditor from go-mql-matrix.ini"},
+	{"doctor", "Checks wine, metaeditor and the MQL4 folder and runs a test compile"},
+	{"baseline <targets>...", "Compiles the targets and snapshots their warnings into go-mql-baseline.json or --baseline"},
+	{"explain <code|message>", "Explains a compiler error or warning and how to fix it"},
+	{"history [targets]...", "Shows the recent builds and the warnings the latest one introduced"},
+}
+
 var HelpStyle = lipgloss.
 	NewStyle().
 	Padding(0, 1).
@@ -66,6 +117,18 @@ func (c *MQLConfig) ParseCLIArgs() {
 		"l",
 	))
 
+	flag.StringVarP(&c.OutDir, "out-dir", "o", "", Highlight(
+		"Copies the .ex4 and writes the logs int%s a build folder mirroring the sources",
+		"o",
+	))
+
+	flag.StringArrayVarP(&c.Defines, "define", "D", nil, Highlight(
+		"%sefines NAME=value for the build, can be repeated",
+		"D",
+	))
+
+	flag.IntVar(&c.Context, "context", 0, "Lines of source shown before and after each diagnostic")
+
 	defaultMetaEditorPath := os.Getenv("MQL4_METAEDITOR_PATH")
 
 	if defaultMetaEditorPath == "" {
@@ -77,6 +140,88 @@ func (c *MQLConfig) ParseCLIArgs() {
 		"m",
 	))
 
+	defaultWine := os.Getenv("MQL4_WINE")
+
+	if defaultWine == "" {
+		defaultWine = "wine"
+	}
+
+	flag.StringVar(&c.Wine, "wine", defaultWine,
+		"Wine binary or wrapper (wine64, \"proton run\", ./wine.sh) \nOr picks from $MQL4_WINE environment variable",
+	)
+	flag.StringVar(&c.WinePrefix, "wine-prefix", os.Getenv("WINEPREFIX"), "Wine prefix to run metaeditor in \nOr picks from $WINEPREFIX environment variable")
+	flag.StringVar(&c.WineDebug, "wine-debug", "-all", "WINEDEBUG channels, -all silences wine's messages and \"\" keeps wine's default")
+	flag.StringArrayVar(&c.WineEnv, "wine-env", nil, "Extra KEY=value environment variable for wine, can be repeated")
+
+	flag.BoolVar(&c.WineServer, "wineserver", false, "Keeps a warm wineserver for the session (package, matrix and the TUI) and stops it on exit")
+	flag.BoolVar(&c.Werror, "werror", false, "Treats warnings as errors, codes set to warning in the severity file stay warnings")
+	flag.StringVar(&c.Severity, "severity", "", "Ini file of code = error|warning|ignore overrides (default go-mql-severity.ini when it exists)")
+	flag.BoolVar(&c.Fix, "fix", false, "Applies the suggested fixes to the sources instead of showing them as a diff")
+	flag.StringVar(&c.Changed, "changed", "", "Compiles the targets changed since the git ref (HEAD by default) or that include a changed header")
+	flag.Lookup("changed").NoOptDefVal = "HEAD"
+	flag.StringVar(&c.Baseline, "baseline", "", "Fails the build on warnings that aren't in the baseline file (see the baseline command)")
+	flag.BoolVar(&c.Timings, "timings", false, "Prints how long each phase of the build took and appends it to "+TimingsFile+" in the user cache folder")
+
+	flag.StringSliceVar(&c.WineDrives, "wine-drive", nil,
+		"Maps a wine drive to a folder (x:=/path), on top of the dosdevices of $WINEPREFIX",
+	)
+
+	flag.BoolVarP(&c.Test, "test", "t", false, Highlight(
+		"Runs the strategy %sester on the EA after a successful compile",
+		"t",
+	))
+
+	defaultTerminalPath := os.Getenv("MQL4_TERMINAL_PATH")
+
+	if defaultTerminalPath == "" {
+		defaultTerminalPath = "../terminal.exe"
+	}
+
+	flag.StringVar(&c.TerminalPath, "terminal", defaultTerminalPath,
+		"Sets the path to the terminal.exe used for backtests \nOr picks from $MQL4_TERMINAL_PATH environment variable",
+	)
+
+	flag.StringVar(&c.TesterSymbol, "symbol", "EURUSD", "Symbol to backtest on")
+	flag.StringVar(&c.TesterPeriod, "period", "H1", "Timeframe to backtest on (M1, M5, M15, M30, H1, H4, D1, W1, MN)")
+	flag.IntVar(&c.TesterModel, "model", 0, "Tester model (0 every tick, 1 control points, 2 open prices only)")
+	flag.StringVar(&c.TesterFrom, "from", "", "Backtest start date (yyyy.mm.dd)")
+	flag.StringVar(&c.TesterTo, "to", "", "Backtest end date (yyyy.mm.dd)")
+	flag.StringVar(&c.TesterSet, "set", "", "The .set file with the EA inputs, relative to the tester folder")
+	flag.StringVar(&c.TesterReport, "report", "tester_report", "Report path, relative to the terminal folder")
+
+	flag.BoolVarP(&c.Deploy, "deploy", "d", false, Highlight(
+		"%seploys the compiled .ex4 into the terminals from --deploy-to",
+		"d",
+	))
+
+	var defaultDeployTo []string
+
+	if deployPaths := os.Getenv("MQL4_DEPLOY_PATHS"); deployPaths != "" {
+		// comma separated like --deploy-to
+		defaultDeployTo = strings.Split(deployPaths, ",")
+	}
+
+	flag.StringSliceVar(&c.DeployTo, "deploy-to", defaultDeployTo,
+		"Terminal data folders to deploy to (comma separated) \nOr picks from $MQL4_DEPLOY_PATHS environment variable",
+	)
+
+	flag.BoolVar(&c.DeploySources, "deploy-sources", false, "Also deploys the .mq4 source next to the .ex4")
+
+	flag.StringVar(&c.PackageName, "package-name", "", "Name of the zip built by the package command (first target by default)")
+	flag.StringVar(&c.PackageVersion, "package-version", "", "Version of the zip built by the package command (#property version by default)")
+
+	flag.StringVar(&c.Description, "description", "", "Rewrites the #property description with the version command")
+	flag.BoolVar(&c.BuildID, "build-id", false, "Embeds the git short hash as #define BUILD_ID with the version command")
+
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage of %s [flags] [command]:\n\nCommands:\n", os.Args[0])
+		for _, command := range Commands {
+			fmt.Fprintf(os.Stderr, "  %-26s %s\n", command[0], command[1])
+		}
+		fmt.Fprintln(os.Stderr, "\nFlags:")
+		flag.PrintDefaults()
+	}
+
 	flag.ErrHelp = errors.New("\n" + HelpStyle.Render("Go-MQL's help & usage menu"))
 	flag.CommandLine.SortFlags = false
 
@@ -85,4 +230,56 @@ func (c *MQLConfig) ParseCLIArgs() {
 	}
 
 	flag.Parse()
+
+	if c.Context < 0 {
+		PrintError(fmt.Errorf("--context must be 0 or more, got %d", c.Context))
+		os.Exit(1)
+	}
+
+	args := flag.Args()
+
+	// a bare --changed takes its ref as the next argument too, --changed=<ref> doesn't
+	if bareFlag("changed") && len(args) > 0 && !isCommand(args[0]) {
+		c.Changed = args[0]
+		args = args[1:]
+	}
+
+	if len(args) > 0 {
+		c.Command = args[0]
+		c.Args = args[1:]
+	}
+
+	if c.Changed != "" {
+		if c.Compile != "" || c.Syntax != "" {
+			PrintError(errors.New("--changed picks the targets itself, it can't be combined with -c or -s"))
+			os.Exit(1)
+		}
+
+		if c.Command != "" {
+			PrintError(fmt.Errorf("--changed takes no targets or commands, got %s", strings.Join(args, " ")))
+			os.Exit(1)
+		}
+	}
+}
+
+// Whether the flag was given without a value, as --name rather than --name=value
+func bareFlag(name string) bool {
+	for _, arg := range os.Args[1:] {
+		if arg == "--" {
+			break
+		}
+		if arg == "--"+name {
+			return true
+		}
+	}
+	return false
+}
+
+func isCommand(arg string) bool {
+	for _, command := range Commands {
+		if strings.Fields(command[0])[0] == arg {
+			return true
+		}
+	}
+	return false
 }
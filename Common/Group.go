@@ -0,0 +1,120 @@
+package Common
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	catppuccin "github.com/catppuccin/go"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/lipgloss/table"
+)
+
+type fileCounts struct {
+	File     string
+	Errors   int
+	Warnings int
+}
+
+func (f fileCounts) String() string {
+	return fmt.Sprintf("%d errors, %d warnings", f.Errors, f.Warnings)
+}
+
+// Sorts the diagnostics by file then line, information messages stay first
+// and in the order metaeditor printed them
+func groupByFile(infos []Info) ([]Info, []fileCounts) {
+	sorted := make([]Info, len(infos))
+	copy(sorted, infos)
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if (a.Type == "information") != (b.Type == "information") {
+			return a.Type == "information"
+		}
+		if a.Type == "information" {
+			return false
+		}
+		if a.ScriptName != b.ScriptName {
+			return a.ScriptName < b.ScriptName
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Char < b.Char
+	})
+
+	var counts []fileCounts
+	for _, info := range sorted {
+		if info.Type == "" || info.Type == "information" {
+			continue
+		}
+
+		if len(counts) == 0 || counts[len(counts)-1].File != info.ScriptName {
+			counts = append(counts, fileCounts{File: info.ScriptName})
+		}
+
+		if info.Type == "error" {
+			counts[len(counts)-1].Errors++
+		} else {
+			counts[len(counts)-1].Warnings++
+		}
+	}
+
+	return sorted, counts
+}
+
+func printFileHeader(counts fileCounts) {
+	if PlainOutput {
+		fmt.Printf("==> %s (%s)\n\n", counts.File, counts)
+		return
+	}
+
+	color := catppuccin.Mocha.Yellow().Hex
+	if counts.Errors > 0 {
+		color = catppuccin.Mocha.Red().Hex
+	}
+
+	fmt.Println(
+		lipgloss.NewStyle().
+			Bold(true).
+			Padding(0, 1).
+			Foreground(lipgloss.Color(catppuccin.Latte.Base().Hex)).
+			Background(lipgloss.Color(color)).
+			Render(counts.File) +
+			" " + FaintStyle.Render(counts.String()),
+	)
+	fmt.Println()
+}
+
+func printFileSummary(counts []fileCounts) {
+	if len(counts) == 0 {
+		return
+	}
+
+	if PlainOutput {
+		for _, c := range counts {
+			fmt.Printf("%-50s %4d errors %4d warnings\n", c.File, c.Errors, c.Warnings)
+		}
+		fmt.Println()
+		return
+	}
+
+	t := table.New().
+		Border(lipgloss.RoundedBorder()).
+		BorderStyle(FaintStyle).
+		StyleFunc(func(row, col int) lipgloss.Style {
+			style := lipgloss.NewStyle().Padding(0, 1)
+			if col > 0 {
+				style = style.Align(lipgloss.Right)
+			}
+			return style
+		}).
+		Headers("File", "Errors", "Warnings")
+
+	for _, c := range counts {
+		t.Row(c.File, strconv.Itoa(c.Errors), strconv.Itoa(c.Warnings))
+	}
+
+	fmt.Println(t.Render())
+	fmt.Println()
+}
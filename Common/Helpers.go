@@ -5,16 +5,21 @@ import (
 	"bytes"
 	"fmt"
 	"os"
+	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
+	"time"
 	"unicode/utf16"
-	"unicode/utf8"
 
 	catppuccin "github.com/catppuccin/go"
 	"github.com/charmbracelet/glamour"
 	"github.com/charmbracelet/huh/spinner"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/charmbracelet/log"
+	"github.com/muesli/termenv"
+	"golang.org/x/exp/rand"
+	"golang.org/x/term"
 	"golang.org/x/text/cases"
 	"golang.org/x/text/language"
 	ini "gopkg.in/ini.v1"
@@ -28,6 +33,8 @@ type Info struct {
 	Line       int
 	Char       int
 	Code       int
+	// a warning the baseline knows of, --werror leaves it a warning
+	Baselined bool
 }
 
 type Diagnostic struct {
@@ -37,6 +44,16 @@ type Diagnostic struct {
 	totalWarnings int
 }
 
+func (d Diagnostic) HasErrors() bool {
+	return d.totalErrors > 0
+}
+
+// The compile time metaeditor reports in its log
+func (d Diagnostic) ElapsedTime() time.Duration {
+	ms, _ := strconv.Atoi(d.elapsedTime)
+	return time.Duration(ms) * time.Millisecond
+}
+
 var Spinners = []spinner.Type{
 	spinner.Line,
 	spinner.Dots,
@@ -104,7 +121,23 @@ func PrintError(err error) {
 
 var Logger *log.Logger = log.New(os.Stderr)
 
+// Set when stdout isn't a terminal or NO_COLOR is set, everything is then
+// printed without colours, spinners, nerd font glyphs or boxes
+var PlainOutput bool
+
+func isPlainOutput() bool {
+	return os.Getenv("NO_COLOR") != "" || !term.IsTerminal(int(os.Stdout.Fd()))
+}
+
 func InitLogger() {
+	PlainOutput = isPlainOutput()
+
+	if PlainOutput {
+		lipgloss.SetColorProfile(termenv.Ascii)
+		log.SetColorProfile(termenv.Ascii)
+		Logger.SetColorProfile(termenv.Ascii)
+	}
+
 	styles := log.DefaultStyles()
 
 	styles.Prefix = FaintStyle.Bold(true)
@@ -134,37 +167,70 @@ func InitLogger() {
 	Logger.SetStyles(styles)
 }
 
+// Runs the action behind a random spinner, or straight away on plain output
+func RunWithSpinner(title string, target string, action func()) {
+	if PlainOutput {
+		fmt.Println(title, target)
+		action()
+		return
+	}
+
+	rand.Seed(uint64(time.Now().Nanosecond()))
+	randomSpinner := Spinners[rand.Intn(len(Spinners))]
+
+	err := spinner.New().
+		Type(randomSpinner).
+		Title(SpinnerStyle.
+			Render(
+				fmt.Sprintf(
+					"%s %s",
+					title,
+					SpinnerTitleStyle.Render(target),
+				),
+			),
+		).
+		Style(lipgloss.NewStyle().Foreground(lipgloss.Color("#F780E2")).PaddingLeft(1)).
+		Action(action).
+		Run()
+	if err != nil {
+		fmt.Println(err)
+	}
+}
+
+// Decodes little endian UTF-16 (metaeditor logs and sources), dropping the BOM
 func DecodeUTF16(b []byte) (string, error) {
 	if len(b)%2 != 0 {
 		return "", fmt.Errorf("must have even length byte slice")
 	}
 
-	u16s := make([]uint16, 1)
+	u16s := make([]uint16, 0, len(b)/2)
+	for i := 0; i < len(b); i += 2 {
+		u16s = append(u16s, uint16(b[i])+(uint16(b[i+1])<<8))
+	}
 
+	// decoding the whole slice keeps surrogate pairs together
 	ret := &bytes.Buffer{}
-
-	b8buf := make([]byte, 4)
-
-	lb := len(b)
-	for i := 0; i < lb; i += 2 {
-		u16s[0] = uint16(b[i]) + (uint16(b[i+1]) << 8)
-		r := utf16.Decode(u16s)
-		n := utf8.EncodeRune(b8buf, r[0])
-		ret.Write(b8buf[:n])
+	for _, r := range utf16.Decode(u16s) {
+		ret.WriteRune(r)
 	}
 
-	return ret.String(), nil
+	return strings.TrimPrefix(ret.String(), "\ufeff"), nil
 }
 
-func removeNonAscii(str string) string {
-	re := regexp.MustCompile("[[:^ascii:]]")
-	t := re.ReplaceAllLiteralString(str, "")
+func EncodeUTF16(s string) []byte {
+	u16s := utf16.Encode([]rune(s))
+
+	b := make([]byte, 0, len(u16s)*2)
+	for _, u := range u16s {
+		b = append(b, byte(u), byte(u>>8))
+	}
 
-	return t
+	return b
 }
 
 func CenterString(str string, width int, color string) string {
-	spaces := int(float64(width-len(str)) / 2)
+	strWidth := lipgloss.Width(str)
+	spaces := max((width-strWidth)/2, 0)
 	fg := lipgloss.NewStyle().Foreground(lipgloss.Color(color))
 	return fg.
 		Render("╭"+strings.Repeat("─", spaces)) +
@@ -175,10 +241,10 @@ func CenterString(str string, width int, color string) string {
 			Foreground(lipgloss.Color("#000000")).
 			Render(str) +
 		fg.
-			Render(strings.Repeat("─", width-(spaces+len(str)))+"╮")
+			Render(strings.Repeat("─", max(width-(spaces+strWidth), 0))+"╮")
 }
 
-func ParseLogFile(outputStr string, status int, mode string) (diagnostics Diagnostic) {
+func ParseLogFile(outputStr string, mode string) (diagnostics Diagnostic) {
 	scanner := bufio.NewScanner(strings.NewReader(outputStr))
 
 	for scanner.Scan() {
@@ -212,14 +278,14 @@ func ParseLogFile(outputStr string, status int, mode string) (diagnostics Diagno
 		info := Info{}
 		if strings.Contains(line, "information:") {
 			parts := strings.Split(line, ": information: ")
-			info.FileName = strings.TrimSpace(parts[0])
+			info.FileName = FromWinePath(strings.TrimSpace(parts[0]))
 			info.Type = "information"
 			info.Message = strings.TrimSpace(parts[1])
 		} else {
 			re := regexp.MustCompile(`^(.*)\((\d+),(\d+)\) : (\w+) (\d+): (.*)$`)
 			matches := re.FindStringSubmatch(line)
 			if len(matches) == 7 {
-				info.ScriptName = matches[1]
+				info.ScriptName = FromWinePath(matches[1])
 				fmt.Sscanf(matches[2], "%d", &info.Line)
 				fmt.Sscanf(matches[3], "%d", &info.Char)
 				info.Type = matches[4]
@@ -230,6 +296,11 @@ func ParseLogFile(outputStr string, status int, mode string) (diagnostics Diagno
 		diagnostics.info = append(diagnostics.info, info)
 	}
 
+	return diagnostics
+}
+
+// Prints whether the build succeeded
+func PrintResult(diagnostics Diagnostic, status int, mode string) {
 	succesMsg := "Compilation successful!"
 	failMsg := "Failed to compile"
 
@@ -253,25 +324,33 @@ func ParseLogFile(outputStr string, status int, mode string) (diagnostics Diagno
 			Render(failMsg),
 		)
 	}
-
-	return diagnostics
 }
 
-func PrintDiagnostics(diagnostics Diagnostic, readFileCache map[string][]string) {
+func PrintDiagnostics(diagnostics Diagnostic, readFileCache map[string][]string, cfg *MQLConfig) {
 	fmt.Println()
-	for _, info := range diagnostics.info {
+
+	infos, counts := groupByFile(diagnostics.info)
+
+	currentFile := -1
+	for _, info := range infos {
 
 		if info.Type == "" {
 			continue
 		}
 
+		// a header for every file that has diagnostics
+		if info.Type != "information" && (currentFile < 0 || counts[currentFile].File != info.ScriptName) {
+			currentFile++
+			printFileHeader(counts[currentFile])
+		}
+
 		if info.Type == "information" {
-			fileName := removeNonAscii(strings.ReplaceAll(info.FileName, "\\", "/"))
+			fileName := info.FileName
 			Logger.Info(cases.Title(language.English).String(strings.Split(info.Message, " ")[0]), "Script", fileName)
 			fmt.Println()
 		} else {
 
-			fileName := removeNonAscii(strings.ReplaceAll(info.ScriptName, "\\", "/"))
+			fileName := info.ScriptName
 			header := fmt.Sprintf(
 				" Script: %s | Char: %d | Type: %s | Code: %d ",
 				info.ScriptName,
@@ -280,96 +359,86 @@ func PrintDiagnostics(diagnostics Diagnostic, readFileCache map[string][]string)
 				info.Code,
 			)
 
-			// check if the file is already in the cache
-			if readFileCache[fileName] == nil {
-				// if not, read the file and save it into the cache
-				fileContents, err := os.ReadFile(fileName)
-				if err != nil {
-					// fmt.Println(err, fileName)
-					panic(err)
-				}
-
-				// add new entry to map
-				readFileCache[fileName] = strings.Split(string(fileContents), "\n")
+			color := catppuccin.Mocha.Yellow().Hex
+			if info.Type == "error" {
+				color = catppuccin.Mocha.Red().Hex
 			}
 
-			diagnosticLine := readFileCache[fileName][info.Line-1]
-
-			// count number of spaces at the beginning of the line
-			var leadingSpaces int
-			for i := 0; i < len(diagnosticLine); i++ {
-				if diagnosticLine[i] == ' ' {
-					leadingSpaces++
+			// check if the file is already in the cache, unreadable files are cached as nil
+			if _, ok := readFileCache[fileName]; !ok {
+				// if not, read the file and save it into the cache
+				fileContents, _, err := ReadSource(fileName)
+				if err == nil {
+					readFileCache[fileName] = strings.Split(string(fileContents), "\n")
 				} else {
-					break
+					readFileCache[fileName] = nil
 				}
 			}
 
-			leadingSpaces = leadingSpaces - 1
-
-			if leadingSpaces < 0 {
-				leadingSpaces = 0
+			// system headers, unmapped paths and lines past EOF only get the message
+			fileLines := readFileCache[fileName]
+			if fileLines == nil {
+				printMessageOnlyDiagnostic(info, header, color, "source not found: "+fileName)
+				continue
 			}
 
-			trimmedDiagnosticLine := diagnosticLine[leadingSpaces:]
-
-			var chunkStart int
-
-			if len(trimmedDiagnosticLine) > 69 {
-				// where does the char fall
-
-				chunkIndex := info.Char / 69
-				chunkStart = chunkIndex * 69
-				chunkEnd := chunkStart + 69
-				chunkEnd = min(chunkEnd, len(trimmedDiagnosticLine))
-
-				if (chunkEnd - chunkStart) < 69 {
-					chunkStart = len(trimmedDiagnosticLine) - 69
-					chunkStart = max(chunkStart, 0)
-				}
-
-				trimmedDiagnosticLine = trimmedDiagnosticLine[chunkStart:chunkEnd]
-
+			if info.Line < 1 || info.Line > len(fileLines) {
+				printMessageOnlyDiagnostic(info, header, color, fmt.Sprintf("line %d is past the end of %s", info.Line, fileName))
+				continue
 			}
 
-			color := catppuccin.Mocha.Yellow().Hex
-			if info.Type == "error" {
-				color = catppuccin.Mocha.Red().Hex
-			}
+			snippet := buildSnippet(fileLines, info, cfg.Context, snippetWidth)
 
-			repeatCount := info.Char - leadingSpaces - chunkStart + 1
+			if PlainOutput {
+				printPlainDiagnostic(info, snippet)
+				continue
+			}
 
-			repeatCount = max(repeatCount, 1)
+			// the caret lines become an indented code block, which eats 4 spaces
+			caretPadding := strings.Repeat(" ", snippet.caretCol+4)
 
 			codeBlock := "```cpp\n" +
-				trimmedDiagnosticLine +
+				strings.Join(snippet.lines, "\n") +
 				"\n```\n" +
-				strings.Repeat(" ", repeatCount) + "  │" +
-				"\n" + strings.
-				Repeat(" ", repeatCount) +
-				"  ╰─➤ " +
+				caretPadding + strings.Repeat("^", snippet.caretLen) +
+				"\n" + caretPadding +
+				"╰─➤ " +
 				lipgloss.NewStyle().Foreground(lipgloss.Color(color)).Render(info.Message)
 
 			out, _ := glamour.Render(codeBlock, "dracula")
 
 			outSplit := strings.Split(out, "\n")
 
-			outSplit = append(outSplit[1:3], outSplit[4:]...)
+			// drop the blank line glamour starts with and the one after the code
+			codeLines := len(snippet.lines)
+			outSplit = append(outSplit[1:2+codeLines], outSplit[3+codeLines:]...)
 			out = strings.Join(outSplit, "\n")
+
+			var lineNumbers []string
+			for i := range snippet.lines {
+				lineNumber := fmt.Sprintf("%5d", snippet.first+i)
+				if snippet.first+i == info.Line {
+					lineNumber = lipgloss.NewStyle().Foreground(lipgloss.Color(color)).Render(lineNumber)
+				}
+				lineNumbers = append(lineNumbers, lineNumber)
+			}
+
+			content := FaintStyle.Render(
+				lipgloss.JoinHorizontal(
+					lipgloss.Top,
+					FaintStyle.Render("\n"+strings.Join(lineNumbers, "\n"))+"\n",
+					FaintStyle.Render("\n"+strings.Repeat(" |\n", codeLines)),
+					strings.TrimRight(out, "\n"),
+				),
+			)
+
 			out = lipgloss.
 				NewStyle().
 				Border(lipgloss.RoundedBorder()).
 				BorderForeground(lipgloss.Color(color)).
-				Render(
-					FaintStyle.Render(
-						lipgloss.JoinHorizontal(
-							lipgloss.Top,
-							FaintStyle.Render(fmt.Sprintf("\n%5d", info.Line))+"\n",
-							FaintStyle.Render("\n |\n"),
-							strings.TrimRight(out, "\n"),
-						),
-					),
-				)
+				// long paths make the header wider than the code
+				Width(max(lipgloss.Width(content), lipgloss.Width(header))).
+				Render(content)
 
 			outSplit = strings.Split(out, "\n")
 
@@ -382,9 +451,12 @@ func PrintDiagnostics(diagnostics Diagnostic, readFileCache map[string][]string)
 			out = centeredHeader + "\n" + out
 
 			fmt.Println(out)
+			printHint(info)
 		}
 	}
 
+	printFileSummary(counts)
+
 	if diagnostics.totalWarnings > 0 {
 		Logger.Warn("Warnings", "Total", diagnostics.totalWarnings)
 		fmt.Println()
@@ -397,15 +469,75 @@ func PrintDiagnostics(diagnostics Diagnostic, readFileCache map[string][]string)
 	Logger.Info("Elapsed Time", "ms", diagnostics.elapsedTime)
 }
 
-func BuildCompileTarget(target string) (compileTarget map[string]string, logfile string) {
-	cfg, err := ini.Load("../config/terminal.ini")
+func printPlainHeader(info Info) {
+	fmt.Printf(
+		"%s(%d,%d) : %s %d: %s\n",
+		info.ScriptName,
+		info.Line,
+		info.Char,
+		info.Type,
+		info.Code,
+		info.Message,
+	)
+}
+
+// Box with the message alone, for diagnostics whose source can't be shown
+func printMessageOnlyDiagnostic(info Info, header, color, reason string) {
+	if PlainOutput {
+		printPlainHeader(info)
+		fmt.Printf("      (%s)\n", reason)
+		printHint(info)
+		fmt.Println()
+		return
+	}
+
+	content := "╰─➤ " + lipgloss.NewStyle().Foreground(lipgloss.Color(color)).Render(info.Message) +
+		"\n" + FaintStyle.Render(reason)
+
+	out := lipgloss.
+		NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color(color)).
+		Padding(1, 2, 0, 2).
+		// wide enough for the header
+		Width(max(lipgloss.Width(content)+4, lipgloss.Width(header))).
+		Render(content)
+
+	outSplit := strings.Split(out, "\n")
+	out = strings.Join(outSplit[1:], "\n")
+
+	fmt.Println(CenterString(header, lipgloss.Width(out)-2, color) + "\n" + out)
+	printHint(info)
+}
+
+// Compiler style diagnostic without colours or boxes, for pipes and CI logs
+func printPlainDiagnostic(info Info, snippet snippet) {
+	printPlainHeader(info)
+
+	for i, line := range snippet.lines {
+		fmt.Printf("%5d | %s\n", snippet.first+i, line)
+	}
+	fmt.Printf(
+		"      | %s%s\n",
+		strings.Repeat(" ", snippet.caretCol),
+		strings.Repeat("^", snippet.caretLen),
+	)
+	printHint(info)
+	fmt.Println()
+}
+
+// the terminal settings, relative to the MQL4 folder of a portable install
+const TerminalIniPath = "../config/terminal.ini"
+
+func BuildCompileTarget(target string, mqlConfig *MQLConfig) (compileTarget map[string]string, logfile string) {
+	cfg, err := ini.Load(TerminalIniPath)
 	if err != nil {
 		fmt.Printf("Fail to read file: %v", err)
 		os.Exit(1)
 	}
 
-	targetPath := strings.Split(target, "/")
-	logfile = strings.Split(targetPath[len(targetPath)-1], ".")[0] + ".log"
+	logfile = OutputPath(target, ".log", mqlConfig)
+	os.MkdirAll(filepath.Dir(logfile), 0o755)
 
 	lang := "MQL4"
 	broker := cfg.Section("Settings").Key("LastScanServer").String()
@@ -0,0 +1,74 @@
+package Common
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+func TestDecodeUTF16(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   []byte
+		want    string
+		wantErr bool
+	}{
+		{"ascii", EncodeUTF16("Result: 0 errors"), "Result: 0 errors", false},
+		{"bom", append([]byte{0xff, 0xfe}, EncodeUTF16("ea.mq4")...), "ea.mq4", false},
+		{"cyrillic", append([]byte{0xff, 0xfe}, EncodeUTF16(`Z:\Experts\Мой советник.mq4`)...), `Z:\Experts\Мой советник.mq4`, false},
+		{"surrogate pair", EncodeUTF16("目标 😀.mq4"), "目标 😀.mq4", false},
+		{"bom only", []byte{0xff, 0xfe}, "", false},
+		{"odd length", []byte{0xff, 0xfe, 0x41}, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecodeUTF16(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DecodeUTF16() error = %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("DecodeUTF16() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncodeUTF16RoundTrip(t *testing.T) {
+	for _, s := range []string{"", "ea.mq4", "Мой советник", "目标 😀"} {
+		got, err := DecodeUTF16(EncodeUTF16(s))
+		if err != nil || got != s {
+			t.Errorf("DecodeUTF16(EncodeUTF16(%q)) = %q, %v", s, got, err)
+		}
+	}
+
+	if !bytes.Equal(EncodeUTF16("A"), []byte{0x41, 0x00}) {
+		t.Errorf("EncodeUTF16 isn't little endian")
+	}
+}
+
+func TestCenterString(t *testing.T) {
+	tests := []struct {
+		name  string
+		str   string
+		width int
+	}{
+		{"ascii", " Script: ea.mq4 ", 40},
+		{"cyrillic", " Script: Мой советник.mq4 ", 40},
+		{"wide runes", " Script: 目标.mq4 ", 40},
+		{"exact", " Script: ea.mq4 ", 16},
+		{"wider than the box", " Script: Experts/Мой советник/очень длинное имя.mq4 ", 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := lipgloss.Width(CenterString(tt.str, tt.width, "#ff0000"))
+
+			want := max(tt.width, lipgloss.Width(tt.str)) + 2
+			if got != want {
+				t.Errorf("CenterString() is %d columns wide, want %d", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,392 @@
+package Common
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	catppuccin "github.com/catppuccin/go"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/lipgloss/table"
+)
+
+// Every build is appended to it as one JSON line, in the workspace's cache folder
+const HistoryFile = "history.jsonl"
+
+// builds of each target shown by the history command
+const historyLimit = 10
+
+// A warning as the history remembers it, the line is left out when comparing
+// since unrelated edits move it around
+type HistoryWarning struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (w HistoryWarning) key() string {
+	return fmt.Sprintf("%s\x00%d\x00%s", w.File, w.Code, w.Message)
+}
+
+type HistoryEntry struct {
+	Target   string           `json:"target"`
+	Mode     string           `json:"mode"`
+	Time     time.Time        `json:"time"`
+	Commit   string           `json:"commit,omitempty"`
+	Defines  []string         `json:"defines,omitempty"`
+	Errors   int              `json:"errors"`
+	Warnings int              `json:"warnings"`
+	Codes    []int            `json:"codes,omitempty"`
+	Warned   []HistoryWarning `json:"warned,omitempty"`
+}
+
+// Builds the history entry of the diagnostics of a build
+func NewHistoryEntry(target, mode string, diagnostics Diagnostic, cfg *MQLConfig) HistoryEntry {
+	entry := HistoryEntry{
+		Target:   cleanTarget(target),
+		Mode:     mode,
+		Time:     time.Now(),
+		Defines:  cfg.Defines,
+		Errors:   diagnostics.totalErrors,
+		Warnings: diagnostics.totalWarnings,
+	}
+
+	entry.Commit = historyCommit(target)
+
+	seen := map[int]bool{}
+	for _, info := range diagnostics.info {
+		if info.Type != "error" && info.Type != "warning" {
+			continue
+		}
+
+		if !seen[info.Code] {
+			seen[info.Code] = true
+			entry.Codes = append(entry.Codes, info.Code)
+		}
+
+		if info.Type == "warning" {
+			entry.Warned = append(entry.Warned, HistoryWarning{
+				File:    info.ScriptName,
+				Line:    info.Line,
+				Code:    info.Code,
+				Message: info.Message,
+			})
+		}
+	}
+	sort.Ints(entry.Codes)
+
+	return entry
+}
+
+// ./Experts/ea.mq4 and Experts/ea.mq4 are the same target
+func cleanTarget(target string) string {
+	return filepath.ToSlash(filepath.Clean(target))
+}
+
+// the commit of each source folder, looked up once per session instead of
+// spawning git for every build
+var (
+	historyCommits   = map[string]string{}
+	historyCommitsMu sync.Mutex
+)
+
+func historyCommit(target string) string {
+	dir := filepath.Dir(target)
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+
+	historyCommitsMu.Lock()
+	defer historyCommitsMu.Unlock()
+
+	commit, ok := historyCommits[dir]
+	if !ok {
+		// not being in a git repo is fine
+		commit, _ = GitShortHash(target)
+		historyCommits[dir] = commit
+	}
+
+	return commit
+}
+
+// The folder of the workspace (the cwd) in the user's cache folder, where the
+// files a build appends to live so they don't dirty the sources
+func workspaceCacheDir() (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+
+	// the folder name keeps it readable, the hash tells apart workspaces named alike
+	sum := sha256.Sum256([]byte(cwd))
+	workspace := fmt.Sprintf("%s-%x", filepath.Base(cwd), sum[:4])
+
+	return filepath.Join(cacheDir, "go-mql-build", workspace), nil
+}
+
+// The history of the workspace
+func HistoryPath() (string, error) {
+	dir, err := workspaceCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, HistoryFile), nil
+}
+
+func SaveHistory(entry HistoryEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	path, err := HistoryPath()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.Write(append(line, '\n'))
+	return err
+}
+
+// Reads the history, oldest first, skipping lines it can't parse
+func LoadHistory() ([]HistoryEntry, error) {
+	path, err := HistoryPath()
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []HistoryEntry
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var entry HistoryEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		entry.Target = cleanTarget(entry.Target)
+		entries = append(entries, entry)
+	}
+
+	return entries, scanner.Err()
+}
+
+// Whether both entries are the same kind of build of the same target with the
+// same defines
+func (e HistoryEntry) sameBuild(other HistoryEntry) bool {
+	return e.Target == other.Target && e.Mode == other.Mode &&
+		strings.Join(e.Defines, " ") == strings.Join(other.Defines, " ")
+}
+
+func (e HistoryEntry) name() string {
+	name := e.Target
+	if len(e.Defines) > 0 {
+		name += " -D " + strings.Join(e.Defines, " -D ")
+	}
+	if e.Mode == "syntax" {
+		name += " (syntax check)"
+	}
+	return name
+}
+
+// Warnings of the current build that the previous one didn't have
+func NewWarnings(previous, current HistoryEntry) []HistoryWarning {
+	known := map[string]int{}
+	for _, warning := range previous.Warned {
+		known[warning.key()]++
+	}
+
+	var added []HistoryWarning
+	for _, warning := range current.Warned {
+		if known[warning.key()] > 0 {
+			known[warning.key()]--
+			continue
+		}
+		added = append(added, warning)
+	}
+
+	return added
+}
+
+func formatDelta(delta int) string {
+	switch {
+	case delta > 0:
+		return "+" + strconv.Itoa(delta)
+	case delta < 0:
+		return strconv.Itoa(delta)
+	}
+	return "="
+}
+
+func printHistoryTable(builds []HistoryEntry) {
+	worseStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(catppuccin.Mocha.Red().Hex))
+	betterStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(catppuccin.Mocha.Green().Hex))
+
+	first := 0
+	if len(builds) > historyLimit {
+		first = len(builds) - historyLimit
+	}
+
+	var rows [][]string
+	for i := first; i < len(builds); i++ {
+		build := builds[i]
+
+		errorsDelta, warningsDelta := "", ""
+		if i > 0 {
+			errorsDelta = formatDelta(build.Errors - builds[i-1].Errors)
+			warningsDelta = formatDelta(build.Warnings - builds[i-1].Warnings)
+		}
+
+		rows = append(rows, []string{
+			build.Time.Local().Format("2006-01-02 15:04"),
+			build.Commit,
+			strconv.Itoa(build.Errors),
+			errorsDelta,
+			strconv.Itoa(build.Warnings),
+			warningsDelta,
+		})
+	}
+
+	if PlainOutput {
+		for _, row := range rows {
+			fmt.Printf("%-16s %-9s %4s errors %3s %4s warnings %3s\n", row[0], row[1], row[2], row[3], row[4], row[5])
+		}
+		fmt.Println()
+		return
+	}
+
+	t := table.New().
+		Border(lipgloss.RoundedBorder()).
+		BorderStyle(FaintStyle).
+		StyleFunc(func(row, col int) lipgloss.Style {
+			style := lipgloss.NewStyle().Padding(0, 1)
+			if col > 1 {
+				style = style.Align(lipgloss.Right)
+			}
+			// row 0 is the header
+			if row > 0 && (col == 3 || col == 5) {
+				switch {
+				case strings.HasPrefix(rows[row-1][col], "+"):
+					style = style.Inherit(worseStyle)
+				case strings.HasPrefix(rows[row-1][col], "-"):
+					style = style.Inherit(betterStyle)
+				}
+			}
+			return style
+		}).
+		Headers("Time", "Commit", "Errors", "Δ", "Warnings", "Δ")
+
+	for _, row := range rows {
+		t.Row(row...)
+	}
+
+	fmt.Println(t.Render())
+	fmt.Println()
+}
+
+func printNewWarnings(previous, current HistoryEntry) {
+	added := NewWarnings(previous, current)
+	if len(added) == 0 {
+		return
+	}
+
+	warnStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(catppuccin.Mocha.Yellow().Hex))
+
+	fmt.Println(Bold.Render(fmt.Sprintf("New warnings since %s", previous.Time.Local().Format("2006-01-02 15:04"))))
+	fmt.Println()
+	for _, warning := range added {
+		location := fmt.Sprintf("%s:%d", warning.File, warning.Line)
+		fmt.Printf("  %s %s %s\n", warnStyle.Render(fmt.Sprintf("%d", warning.Code)), FaintStyle.Render(location), warning.Message)
+	}
+	fmt.Println()
+}
+
+// Prints the last builds of the targets, all of them when none are given, and
+// the warnings their latest build introduced
+func PrintHistory(entries []HistoryEntry, targets []string) {
+	var groups [][]HistoryEntry
+
+	for _, entry := range entries {
+		if len(targets) > 0 && !containsPath(targets, entry.Target) {
+			continue
+		}
+
+		found := false
+		for i := range groups {
+			if groups[i][0].sameBuild(entry) {
+				groups[i] = append(groups[i], entry)
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			groups = append(groups, []HistoryEntry{entry})
+		}
+	}
+
+	if len(groups) == 0 {
+		path, _ := HistoryPath()
+		Logger.Info("No builds in the history yet", "file", path)
+		return
+	}
+
+	for _, builds := range groups {
+		fmt.Println()
+		if PlainOutput {
+			fmt.Printf("==> %s\n\n", builds[0].name())
+		} else {
+			fmt.Println(Bold.Render(builds[0].name()))
+		}
+
+		printHistoryTable(builds)
+
+		if len(builds) > 1 {
+			printNewWarnings(builds[len(builds)-2], builds[len(builds)-1])
+		}
+	}
+}
+
+func containsPath(paths []string, path string) bool {
+	for _, p := range paths {
+		if cleanTarget(p) == cleanTarget(path) {
+			return true
+		}
+	}
+	return false
+}
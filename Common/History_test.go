@@ -0,0 +1,93 @@
+package Common
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestContainsPath(t *testing.T) {
+	tests := []struct {
+		paths []string
+		path  string
+		want  bool
+	}{
+		{[]string{"./Experts/ea.mq4"}, "Experts/ea.mq4", true},
+		{[]string{"Experts/ea.mq4"}, "./Experts/ea.mq4", true},
+		{[]string{"Experts//ea.mq4"}, "Experts/ea.mq4", true},
+		{[]string{"Experts/ea.mq4"}, "Experts/ea2.mq4", false},
+	}
+
+	for _, tt := range tests {
+		if got := containsPath(tt.paths, tt.path); got != tt.want {
+			t.Errorf("containsPath(%q, %q) = %v, want %v", tt.paths, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestSaveHistoryOutsideTheWorkspace(t *testing.T) {
+	chdirTemp(t)
+
+	cache := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", cache)
+	t.Setenv("HOME", cache)
+
+	for _, target := range []string{"./Experts/ea.mq4", "Experts/ea.mq4"} {
+		entry := NewHistoryEntry(target, "compile", Diagnostic{}, &MQLConfig{})
+		if err := SaveHistory(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := os.Stat(HistoryFile); err == nil {
+		t.Errorf("SaveHistory() wrote %s into the workspace", HistoryFile)
+	}
+
+	path, err := HistoryPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resolved, _ := filepath.EvalSymlinks(cache); !strings.HasPrefix(path, cache) && !strings.HasPrefix(path, resolved) {
+		t.Errorf("HistoryPath() = %q, want it in the cache folder %q", path, cache)
+	}
+
+	entries, err := LoadHistory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].Target != entries[1].Target {
+		t.Errorf("LoadHistory() = %+v, want two builds of the same target", entries)
+	}
+}
+
+func TestSaveTimingsOutsideTheWorkspace(t *testing.T) {
+	chdirTemp(t)
+
+	cache := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", cache)
+	t.Setenv("HOME", cache)
+
+	if err := SaveTimings(Timings{Target: "Experts/ea.mq4", Mode: "compile"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(TimingsFile); err == nil {
+		t.Errorf("SaveTimings() wrote %s into the workspace", TimingsFile)
+	}
+
+	timingsPath, err := TimingsPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	historyPath, err := HistoryPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Dir(timingsPath) != filepath.Dir(historyPath) {
+		t.Errorf("TimingsPath() = %q, want it next to the history %q", timingsPath, historyPath)
+	}
+	if _, err := os.Stat(timingsPath); err != nil {
+		t.Errorf("SaveTimings() didn't write %s: %v", timingsPath, err)
+	}
+}
@@ -0,0 +1,55 @@
+package Common
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var includeRe = regexp.MustCompile(`(?m)^\s*#include\s*([<"])([^>"]+)[>"]`)
+
+// Resolves the #include lines of the source, quoted ones are relative to the
+// including file and <> ones to the Include folder, missing files are left out
+func includesOf(path string, source string) []string {
+	var includes []string
+
+	for _, match := range includeRe.FindAllStringSubmatch(source, -1) {
+		name := filepath.FromSlash(strings.ReplaceAll(strings.TrimSpace(match[2]), "\\", "/"))
+
+		var resolved string
+		if match[1] == "\"" {
+			resolved = filepath.Join(filepath.Dir(path), name)
+		} else {
+			resolved = filepath.Join("Include", name)
+		}
+
+		if _, err := os.Stat(resolved); err == nil {
+			includes = append(includes, filepath.Clean(resolved))
+		}
+	}
+
+	return includes
+}
+
+// The target with every file it includes, directly or not
+func collectIncludes(target string) []string {
+	files := []string{filepath.Clean(target)}
+	seen := map[string]bool{filepath.Clean(target): true}
+
+	for i := 0; i < len(files); i++ {
+		source, _, err := ReadSource(files[i])
+		if err != nil {
+			continue
+		}
+
+		for _, include := range includesOf(files[i], string(source)) {
+			if !seen[include] {
+				seen[include] = true
+				files = append(files, include)
+			}
+		}
+	}
+
+	return files
+}
@@ -0,0 +1,149 @@
+package Common
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	catppuccin "github.com/catppuccin/go"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/lipgloss/table"
+	ini "gopkg.in/ini.v1"
+)
+
+const DefaultMatrixFile = "go-mql-matrix.ini"
+
+type MatrixEditor struct {
+	Name string
+	Path string
+}
+
+type MatrixVariant struct {
+	Name    string
+	Defines []string
+}
+
+// Targets × variants × metaeditors to build, read from an ini like
+//
+//	targets = Experts/ea.mq4, Experts/grid.mq4
+//
+//	[metaeditor]
+//	build-1090 = ../old/metaeditor.exe
+//	current    = ../metaeditor.exe
+//
+//	[variant.demo]
+//	DEMO   =
+//	BROKER = "ICMarkets"
+type Matrix struct {
+	Targets  []string
+	Editors  []MatrixEditor
+	Variants []MatrixVariant
+}
+
+type MatrixResult struct {
+	Target  string
+	Variant string
+	Editor  string
+	Passed  bool
+	Logfile string
+}
+
+func LoadMatrix(path string, cfg *MQLConfig) (Matrix, error) {
+	var matrix Matrix
+
+	file, err := ini.LoadSources(ini.LoadOptions{PreserveSurroundedQuote: true}, path)
+	if err != nil {
+		return matrix, err
+	}
+
+	matrix.Targets = file.Section("").Key("targets").Strings(",")
+
+	for _, key := range file.Section("metaeditor").Keys() {
+		matrix.Editors = append(matrix.Editors, MatrixEditor{Name: key.Name(), Path: key.String()})
+	}
+
+	for _, section := range file.Sections() {
+		name, ok := strings.CutPrefix(section.Name(), "variant.")
+		if !ok {
+			continue
+		}
+
+		variant := MatrixVariant{Name: name}
+		for _, key := range section.Keys() {
+			define := key.Name()
+			if key.String() != "" {
+				define += "=" + key.String()
+			}
+			variant.Defines = append(variant.Defines, define)
+		}
+		matrix.Variants = append(matrix.Variants, variant)
+	}
+
+	// missing axes fall back to the current settings
+	if len(matrix.Editors) == 0 {
+		matrix.Editors = []MatrixEditor{{Name: "default", Path: cfg.MetaEditorPath}}
+	}
+
+	if len(matrix.Variants) == 0 {
+		matrix.Variants = []MatrixVariant{{Name: "default", Defines: cfg.Defines}}
+	}
+
+	return matrix, nil
+}
+
+// Output directory of one combination, so variants don't overwrite each other
+func MatrixOutDir(cfg *MQLConfig, editor MatrixEditor, variant MatrixVariant) string {
+	outDir := cfg.OutDir
+	if outDir == "" {
+		outDir = "matrix"
+	}
+	return filepath.Join(outDir, editor.Name, variant.Name)
+}
+
+func PrintMatrixGrid(matrix Matrix, results []MatrixResult) {
+	if PlainOutput {
+		fmt.Println()
+		for _, result := range results {
+			if result.Passed {
+				fmt.Printf("[pass] %s %s %s\n", result.Target, result.Variant, result.Editor)
+			} else {
+				fmt.Printf("[fail] %s %s %s %s\n", result.Target, result.Variant, result.Editor, result.Logfile)
+			}
+		}
+		return
+	}
+
+	passStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(catppuccin.Mocha.Green().Hex))
+	failStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(catppuccin.Mocha.Red().Hex))
+
+	headers := []string{"Target", "Variant"}
+	for _, editor := range matrix.Editors {
+		headers = append(headers, editor.Name)
+	}
+
+	t := table.New().
+		Border(lipgloss.RoundedBorder()).
+		BorderStyle(FaintStyle).
+		StyleFunc(func(row, col int) lipgloss.Style {
+			return lipgloss.NewStyle().Padding(0, 1)
+		}).
+		Headers(headers...)
+
+	// results come in target, variant, editor order
+	for i := 0; i < len(results); i += len(matrix.Editors) {
+		row := []string{results[i].Target, results[i].Variant}
+
+		for _, result := range results[i : i+len(matrix.Editors)] {
+			if result.Passed {
+				row = append(row, passStyle.Render("✓ pass"))
+			} else {
+				row = append(row, failStyle.Render("✗ fail")+" "+FaintStyle.Render(result.Logfile))
+			}
+		}
+
+		t.Row(row...)
+	}
+
+	fmt.Println()
+	fmt.Println(t.Render())
+}
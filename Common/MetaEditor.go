@@ -0,0 +1,39 @@
+package Common
+
+import (
+	"os/exec"
+	"runtime"
+)
+
+// Command running metaeditor on the target with the extra switches (like /s)
+func metaEditorCommand(cfg *MQLConfig, target, logfile string, extra ...string) *exec.Cmd {
+	// MT4 should be on portable mode
+
+	// MetaQuotes documents the switches with the quotes around the path only,
+	// /compile:"C:\an ea\ea.mq4", and that's the form used on windows. Under wine
+	// it can't be passed: wine rebuilds the windows command line from the
+	// arguments, quoting every argument with a space as a whole and escaping the
+	// quotes inside it, so metaeditor gets "/compile:Z:\an ea\ea.mq4" either way.
+	// Neither form has been checked against a real metaeditor by the tests, which
+	// run a fake one.
+	if runtime.GOOS == "windows" {
+		args := append([]string{
+			`/compile:"` + target + `"`,
+			`/log:"` + logfile + `"`,
+		}, extra...)
+
+		cmd := exec.Command(cfg.MetaEditorPath)
+		setRawCommandLine(cmd, cfg.MetaEditorPath, args)
+		return cmd
+	}
+
+	// for linux and mac, metaeditor only understands windows paths, every switch
+	// is one argument so wine keeps the spaces of the path inside it
+
+	args := append([]string{
+		"/compile:" + ToWinePath(target),
+		"/log:" + ToWinePath(logfile),
+	}, extra...)
+
+	return wineCommand(cfg, cfg.MetaEditorPath, args...)
+}
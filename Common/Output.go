@@ -0,0 +1,65 @@
+package Common
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Where a build output of the target goes, mirroring the source tree inside
+// the output directory or in the cwd when there is none
+func OutputPath(target, ext string, cfg *MQLConfig) string {
+	name := strings.TrimSuffix(filepath.Base(target), filepath.Ext(target)) + ext
+
+	if cfg.OutDir == "" {
+		return name
+	}
+
+	relDir := "."
+
+	cwd, cwdErr := os.Getwd()
+	absTarget, targetErr := filepath.Abs(target)
+	if cwdErr == nil && targetErr == nil {
+		rel, err := filepath.Rel(cwd, filepath.Dir(absTarget))
+		// sources outside of the cwd land at the root of the output directory
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			relDir = rel
+		}
+	}
+
+	return filepath.Join(cfg.OutDir, relDir, name)
+}
+
+func moveFile(src, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+
+	// rename fails across devices
+	if err := copyFile(src, dst); err != nil {
+		return err
+	}
+
+	return os.Remove(src)
+}
+
+// Copies the verified .ex4 into the output directory if one is set, the one
+// next to the source stays for the terminal and the tester
+func StageArtifact(target string, artifact Artifact, cfg *MQLConfig) (Artifact, error) {
+	if cfg.OutDir == "" {
+		return artifact, nil
+	}
+
+	dest := OutputPath(target, ".ex4", cfg)
+	if err := copyFile(artifact.Path, dest); err != nil {
+		return artifact, err
+	}
+
+	artifact.Path = dest
+
+	return artifact, nil
+}
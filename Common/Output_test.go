@@ -0,0 +1,56 @@
+package Common
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOutputPath(t *testing.T) {
+	chdirTemp(t)
+
+	if err := os.MkdirAll("..hidden", 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &MQLConfig{OutDir: "build"}
+
+	tests := []struct {
+		target string
+		want   string
+	}{
+		{"Experts/ea.mq4", filepath.Join("build", "Experts", "ea.log")},
+		{"..hidden/ea.mq4", filepath.Join("build", "..hidden", "ea.log")},
+		{"../outside/ea.mq4", filepath.Join("build", "ea.log")},
+		{"../ea.mq4", filepath.Join("build", "ea.log")},
+	}
+
+	for _, tt := range tests {
+		if got := OutputPath(tt.target, ".log", cfg); got != tt.want {
+			t.Errorf("OutputPath(%q) = %q, want %q", tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestStageArtifactKeepsSource(t *testing.T) {
+	chdirTemp(t)
+
+	source := filepath.Join("Experts", "ea.ex4")
+	if err := os.WriteFile(source, []byte("EX4"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	artifact, err := StageArtifact("Experts/ea.mq4", Artifact{Path: source}, &MQLConfig{OutDir: "build"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := filepath.Join("build", "Experts", "ea.ex4"); artifact.Path != want {
+		t.Errorf("StageArtifact() path = %q, want %q", artifact.Path, want)
+	}
+	for _, path := range []string{source, artifact.Path} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("%s is missing after staging: %v", path, err)
+		}
+	}
+}
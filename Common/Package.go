@@ -0,0 +1,177 @@
+package Common
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+type ManifestEntry struct {
+	Name      string   `json:"name"`
+	Type      string   `json:"type"`
+	Version   string   `json:"version"`
+	Copyright string   `json:"copyright"`
+	Source    string   `json:"source"`
+	Artifact  string   `json:"artifact"`
+	Size      int64    `json:"size"`
+	SHA256    string   `json:"sha256"`
+	Presets   []string `json:"presets,omitempty"`
+}
+
+type Manifest struct {
+	Name      string          `json:"name"`
+	Version   string          `json:"version"`
+	BuildDate string          `json:"build_date"`
+	Programs  []ManifestEntry `json:"programs"`
+}
+
+// .set files for the target (ea.set, ea_*.set) from MQL4/Presets and next to
+// the source, MQL4/Presets wins when both have the same file
+func findPresets(target string) []string {
+	base := strings.TrimSuffix(filepath.Base(target), filepath.Ext(target))
+
+	seen := map[string]bool{}
+	var presets []string
+	for _, dir := range []string{"Presets", filepath.Dir(target)} {
+		for _, pattern := range []string{base + ".set", base + "_*.set"} {
+			matches, _ := filepath.Glob(filepath.Join(dir, pattern))
+			for _, match := range matches {
+				name := filepath.Base(match)
+				if !seen[name] {
+					seen[name] = true
+					presets = append(presets, match)
+				}
+			}
+		}
+	}
+
+	return presets
+}
+
+func createInZip(w *zip.Writer, name string, modified time.Time) (io.Writer, error) {
+	return w.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: modified,
+	})
+}
+
+func addFileToZip(w *zip.Writer, src, name string, modified time.Time) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := createInZip(w, name, modified)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(out, in)
+	return err
+}
+
+// Bundles the compiled targets, their presets and a manifest into a versioned zip
+func Package(targets []string, artifacts []Artifact, cfg *MQLConfig) (_ string, err error) {
+	if len(targets) == 0 || len(targets) != len(artifacts) {
+		return "", errors.New("Nothing to package")
+	}
+
+	buildDate := time.Now()
+
+	manifest := Manifest{
+		Name:      cfg.PackageName,
+		Version:   cfg.PackageVersion,
+		BuildDate: buildDate.Format(time.RFC3339),
+	}
+
+	if manifest.Name == "" {
+		manifest.Name = strings.TrimSuffix(filepath.Base(targets[0]), filepath.Ext(targets[0]))
+	}
+
+	if manifest.Version == "" {
+		manifest.Version = ReadFileProperty(targets[0], "version")
+	}
+
+	if manifest.Version == "" {
+		manifest.Version = buildDate.Format("20060102")
+	}
+
+	zipPath := filepath.Join(cfg.OutDir, fmt.Sprintf("%s-%s.zip", manifest.Name, manifest.Version))
+
+	if err := os.MkdirAll(filepath.Dir(zipPath), 0o755); err != nil {
+		return "", err
+	}
+
+	zipFile, err := os.Create(zipPath)
+	if err != nil {
+		return "", err
+	}
+
+	// a half written zip must not pass for a release
+	defer func() {
+		zipFile.Close()
+		if err != nil {
+			os.Remove(zipPath)
+		}
+	}()
+
+	w := zip.NewWriter(zipFile)
+
+	for i, target := range targets {
+		artifact := artifacts[i]
+		programType := ProgramType(target)
+
+		entry := ManifestEntry{
+			Name:      strings.TrimSuffix(filepath.Base(target), filepath.Ext(target)),
+			Type:      programType,
+			Version:   ReadFileProperty(target, "version"),
+			Copyright: ReadFileProperty(target, "copyright"),
+			Source:    filepath.ToSlash(target),
+			Artifact:  programType + "/" + filepath.Base(artifact.Path),
+			Size:      artifact.Size,
+			SHA256:    artifact.Hash,
+		}
+
+		if err := addFileToZip(w, artifact.Path, entry.Artifact, buildDate); err != nil {
+			return "", err
+		}
+
+		for _, preset := range findPresets(target) {
+			name := "Presets/" + filepath.Base(preset)
+			if err := addFileToZip(w, preset, name, buildDate); err != nil {
+				return "", err
+			}
+			entry.Presets = append(entry.Presets, name)
+		}
+
+		manifest.Programs = append(manifest.Programs, entry)
+	}
+
+	manifestJSON, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	out, err := createInZip(w, "manifest.json", buildDate)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := out.Write(manifestJSON); err != nil {
+		return "", err
+	}
+
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+
+	return zipPath, nil
+}
@@ -0,0 +1,39 @@
+package Common
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFindPresets(t *testing.T) {
+	chdirTemp(t)
+
+	if err := os.MkdirAll("Presets", 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	files := []string{
+		"Presets/ea.set",
+		"Presets/ea_eurusd.set",
+		"Presets/ea2.set",
+		"Experts/ea.set",
+		"Experts/ea_gbpusd.set",
+		"Experts/eager.set",
+	}
+	for _, file := range files {
+		if err := os.WriteFile(file, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := []string{
+		filepath.Join("Presets", "ea.set"),
+		filepath.Join("Presets", "ea_eurusd.set"),
+		filepath.Join("Experts", "ea_gbpusd.set"),
+	}
+	if got := findPresets("Experts/ea.mq4"); !reflect.DeepEqual(got, want) {
+		t.Errorf("findPresets() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,182 @@
+package Common
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	lastPropertyRe = regexp.MustCompile(`(?m)^[ \t]*#property[^\n]*\n`)
+	buildIDRe      = regexp.MustCompile(`(?m)^[ \t]*#define[ \t]+BUILD_ID[^\r\n]*`)
+	mqlVersionRe   = regexp.MustCompile(`^(\d+)\.(\d{1,2})$`)
+)
+
+func propertyRe(name string) *regexp.Regexp {
+	return regexp.MustCompile(`(?m)^[ \t]*#property[ \t]+` + regexp.QuoteMeta(name) + `[ \t]+"([^"]*)"`)
+}
+
+// Returns the value of a quoted #property of the source, or "" if it's missing
+func ReadProperty(source []byte, name string) string {
+	matches := propertyRe(name).FindSubmatch(source)
+	if matches == nil {
+		return ""
+	}
+	return string(matches[1])
+}
+
+func ReadFileProperty(target, name string) string {
+	source, _, err := ReadSource(target)
+	if err != nil {
+		return ""
+	}
+	return ReadProperty(source, name)
+}
+
+// Reads an MQL source as UTF-8, metaeditor saves files with non-ASCII text as UTF-16
+func ReadSource(path string) (source []byte, isUTF16 bool, err error) {
+	source, err = os.ReadFile(path)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if len(source) > 1 && source[0] == 0xff && source[1] == 0xfe {
+		decoded, err := DecodeUTF16(source[2:])
+		if err != nil {
+			return nil, false, err
+		}
+		return []byte(decoded), true, nil
+	}
+
+	return source, false, nil
+}
+
+// Writes an MQL source back in the encoding it was read in, keeping its mode
+func WriteSource(path string, source []byte, isUTF16 bool) error {
+	if isUTF16 {
+		source = append([]byte{0xff, 0xfe}, EncodeUTF16(string(source))...)
+	}
+
+	mode := os.FileMode(0o644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	return os.WriteFile(path, source, mode)
+}
+
+// Inserts the line after the last #property, or at the top of the source
+func insertAfterProperties(source []byte, line string) []byte {
+	at := 0
+	if all := lastPropertyRe.FindAllIndex(source, -1); all != nil {
+		at = all[len(all)-1][1]
+	}
+
+	newline := "\n"
+	if bytes.Contains(source, []byte("\r\n")) {
+		newline = "\r\n"
+	}
+
+	out := append([]byte{}, source[:at]...)
+	out = append(out, line+newline...)
+	return append(out, source[at:]...)
+}
+
+// Rewrites a quoted #property of the source, adding it if it's missing
+func WriteProperty(source []byte, name, value string) []byte {
+	re := propertyRe(name)
+
+	if loc := re.FindSubmatchIndex(source); loc != nil {
+		out := append([]byte{}, source[:loc[2]]...)
+		out = append(out, value...)
+		return append(out, source[loc[3]:]...)
+	}
+
+	return insertAfterProperties(source, fmt.Sprintf("#property %s \"%s\"", name, value))
+}
+
+// Rewrites (or adds) the BUILD_ID define of the source
+func WriteBuildID(source []byte, buildID string) []byte {
+	define := fmt.Sprintf("#define BUILD_ID \"%s\"", buildID)
+
+	if buildIDRe.Match(source) {
+		return buildIDRe.ReplaceAllLiteral(source, []byte(define))
+	}
+
+	return insertAfterProperties(source, define)
+}
+
+// MQL versions are x.yy, the tens of yy are the minor and the ones the patch
+func parseMQLVersion(version string) (major, minor, patch int, err error) {
+	if version == "" {
+		return 1, 0, 0, nil
+	}
+
+	match := mqlVersionRe.FindStringSubmatch(version)
+	if match == nil {
+		return 0, 0, 0, fmt.Errorf("Invalid version %q, versions are x.yy", version)
+	}
+
+	major, err = strconv.Atoi(match[1])
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("Invalid version %q, versions are x.yy", version)
+	}
+
+	// "1.1" is "1.10"
+	yy, _ := strconv.Atoi((match[2] + "0")[:2])
+
+	return major, yy / 10, yy % 10, nil
+}
+
+// Bumps a x.yy version by major, minor or patch, carrying over 9
+func BumpVersion(version, part string) (string, error) {
+	if part != "major" && part != "minor" && part != "patch" {
+		// an explicit version replaces whatever the source has
+		if !mqlVersionRe.MatchString(part) {
+			return "", fmt.Errorf("Invalid version %q, use major, minor, patch or x.yy", part)
+		}
+		return part, nil
+	}
+
+	major, minor, patch, err := parseMQLVersion(version)
+	if err != nil {
+		return "", err
+	}
+
+	switch part {
+	case "major":
+		major, minor, patch = major+1, 0, 0
+	case "minor":
+		minor, patch = minor+1, 0
+	case "patch":
+		patch++
+	}
+
+	if patch > 9 {
+		minor, patch = minor+1, 0
+	}
+
+	if minor > 9 {
+		major, minor = major+1, 0
+	}
+
+	return fmt.Sprintf("%d.%d%d", major, minor, patch), nil
+}
+
+// Short hash of the commit the target is checked out at
+func GitShortHash(target string) (string, error) {
+	cmd := exec.Command("git", "rev-parse", "--short", "HEAD")
+	cmd.Dir = filepath.Dir(target)
+
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("Failed to read the git commit: %w", err)
+	}
+
+	return strings.TrimSpace(string(out)), nil
+}
@@ -0,0 +1,32 @@
+package Common
+
+import "testing"
+
+func TestBumpVersion(t *testing.T) {
+	tests := []struct {
+		version string
+		part    string
+		want    string
+		wantErr bool
+	}{
+		{"", "patch", "1.01", false},
+		{"1.00", "patch", "1.01", false},
+		{"1.09", "patch", "1.10", false},
+		{"1.99", "patch", "2.00", false},
+		{"1.1", "minor", "1.20", false},
+		{"1.25", "major", "2.00", false},
+		{"1.00", "2.5", "2.5", false},
+		{"1.00", "1.123", "", true},
+		{"1.00", "bogus", "", true},
+		{"1.00", "1.", "", true},
+		{"1.123", "patch", "", true},
+		{"v1.00", "minor", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := BumpVersion(tt.version, tt.part)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("BumpVersion(%q, %q) = %q, %v, want %q (error %v)", tt.version, tt.part, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
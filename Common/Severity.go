@@ -0,0 +1,89 @@
+package Common
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strconv"
+
+	ini "gopkg.in/ini.v1"
+)
+
+// Read when it exists, --severity points to another one
+const DefaultSeverityFile = "go-mql-severity.ini"
+
+// Reads the code = error|warning|ignore overrides of the severity file
+func LoadSeverity(cfg *MQLConfig) (map[int]string, error) {
+	path := cfg.Severity
+	if path == "" {
+		path = DefaultSeverityFile
+		if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+	}
+
+	file, err := ini.Load(path)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read the severity file: %w", err)
+	}
+
+	overrides := map[int]string{}
+	for _, key := range file.Section("").Keys() {
+		code, err := strconv.Atoi(key.Name())
+		if err != nil {
+			return nil, fmt.Errorf("%s: %q is not a compiler code", path, key.Name())
+		}
+
+		switch severity := key.String(); severity {
+		case "error", "warning", "ignore":
+			overrides[code] = severity
+		default:
+			return nil, fmt.Errorf("%s: code %d has to be error, warning or ignore, not %q", path, code, severity)
+		}
+	}
+
+	return overrides, nil
+}
+
+// Changes the type of the diagnostics by code, --werror turns the warnings
+// without an override into errors unless the baseline knows of them
+func ApplySeverity(diagnostics Diagnostic, overrides map[int]string, werror bool) Diagnostic {
+	var infos []Info
+
+	for _, info := range diagnostics.info {
+		if info.Type != "error" && info.Type != "warning" {
+			infos = append(infos, info)
+			continue
+		}
+
+		severity, ok := overrides[info.Code]
+		if !ok {
+			severity = info.Type
+			if werror && !info.Baselined {
+				severity = "error"
+			}
+		}
+
+		if info.Type == "error" {
+			diagnostics.totalErrors--
+		} else {
+			diagnostics.totalWarnings--
+		}
+
+		switch severity {
+		case "ignore":
+			continue
+		case "error":
+			diagnostics.totalErrors++
+		default:
+			diagnostics.totalWarnings++
+		}
+
+		info.Type = severity
+		infos = append(infos, info)
+	}
+
+	diagnostics.info = infos
+	return diagnostics
+}
@@ -0,0 +1,148 @@
+package Common
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// metaeditor's default tab size
+const tabWidth = 3
+
+// width of the code shown in the diagnostic boxes
+const snippetWidth = 69
+
+// Source lines around a diagnostic, ready to be rendered
+type snippet struct {
+	lines []string
+	// line number of lines[0]
+	first int
+	// display column and width of the token the diagnostic points at
+	caretCol int
+	caretLen int
+}
+
+func expandTabs(line string) string {
+	if !strings.Contains(line, "\t") {
+		return line
+	}
+
+	var b strings.Builder
+	col := 0
+	for _, r := range line {
+		if r == '\t' {
+			spaces := tabWidth - col%tabWidth
+			b.WriteString(strings.Repeat(" ", spaces))
+			col += spaces
+			continue
+		}
+		b.WriteRune(r)
+		col += runewidth.RuneWidth(r)
+	}
+
+	return b.String()
+}
+
+// Display column of the char-th (1 based) character once tabs are expanded
+func displayColumn(line string, char int) int {
+	runes := []rune(line)
+	char = min(max(char-1, 0), len(runes))
+	return runewidth.StringWidth(expandTabs(string(runes[:char])))
+}
+
+func isIdentRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// Display width of the identifier or number at the char-th character, or 1
+func tokenWidth(line string, char int) int {
+	runes := []rune(line)
+	start := char - 1
+	if start < 0 || start >= len(runes) || !isIdentRune(runes[start]) {
+		return 1
+	}
+
+	end := start
+	for end < len(runes) && isIdentRune(runes[end]) {
+		end++
+	}
+
+	return max(runewidth.StringWidth(string(runes[start:end])), 1)
+}
+
+// Cuts the display columns [start, end) out of a line without splitting runes
+func sliceColumns(line string, start, end int) string {
+	var b strings.Builder
+	col := 0
+	for _, r := range line {
+		w := runewidth.RuneWidth(r)
+		if col >= start && col+w <= end {
+			b.WriteRune(r)
+		}
+		col += w
+	}
+	return b.String()
+}
+
+func leadingSpaces(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+// Builds the snippet for the diagnostic with context lines before and after,
+// dropping the common indentation and cutting long lines to width (0 keeps them)
+func buildSnippet(fileLines []string, info Info, context int, width int) snippet {
+	context = max(context, 0)
+	from := max(info.Line-context, 1)
+	to := min(info.Line+context, len(fileLines))
+
+	s := snippet{first: from}
+
+	diagnosticLine := strings.TrimRight(fileLines[info.Line-1], "\r")
+	col := displayColumn(diagnosticLine, info.Char)
+	s.caretLen = tokenWidth(diagnosticLine, info.Char)
+
+	indent := col
+	for i := from; i <= to; i++ {
+		line := expandTabs(strings.TrimRight(fileLines[i-1], "\r"))
+		if strings.TrimSpace(line) != "" {
+			indent = min(indent, leadingSpaces(line))
+		}
+		s.lines = append(s.lines, line)
+	}
+
+	// keep a space of indentation like metaeditor does
+	indent = max(indent-1, 0)
+
+	for i, line := range s.lines {
+		if len(line) >= indent {
+			s.lines[i] = line[indent:]
+		} else {
+			s.lines[i] = ""
+		}
+	}
+	col -= indent
+
+	var chunkStart int
+
+	lineWidth := runewidth.StringWidth(s.lines[info.Line-from])
+	if width > 0 && lineWidth > width {
+		// show the chunk the char falls in
+		chunkStart = (col / width) * width
+
+		if chunkStart+width > lineWidth {
+			chunkStart = max(lineWidth-width, 0)
+		}
+
+		for i, line := range s.lines {
+			s.lines[i] = sliceColumns(line, chunkStart, chunkStart+width)
+		}
+	}
+
+	s.caretCol = col - chunkStart
+	if width > 0 {
+		s.caretLen = max(min(s.caretLen, width-s.caretCol), 1)
+	}
+
+	return s
+}
@@ -0,0 +1,163 @@
+package Common
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExpandTabs(t *testing.T) {
+	tests := []struct {
+		line string
+		want string
+	}{
+		{"\tx", "   x"},
+		{"a\tb", "a  b"},
+		{"abc\td", "abc   d"},
+		{"\t\tx", "      x"},
+		{"Д\tx", "Д  x"},
+		{"中\tx", "中 x"},
+	}
+
+	for _, tt := range tests {
+		if got := expandTabs(tt.line); got != tt.want {
+			t.Errorf("expandTabs(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestDisplayColumn(t *testing.T) {
+	tests := []struct {
+		line string
+		char int
+		want int
+	}{
+		{"foo", 1, 0},
+		{"\tfoo", 2, 3},
+		{"a\tfoo", 3, 3},
+		{"Мой = 1", 5, 4},
+		{"中文 = 1", 3, 4},
+		{"ab", 10, 2},
+		{"ab", 0, 0},
+	}
+
+	for _, tt := range tests {
+		if got := displayColumn(tt.line, tt.char); got != tt.want {
+			t.Errorf("displayColumn(%q, %d) = %d, want %d", tt.line, tt.char, got, tt.want)
+		}
+	}
+}
+
+func TestTokenWidth(t *testing.T) {
+	tests := []struct {
+		line string
+		char int
+		want int
+	}{
+		{"   foo = 1;", 4, 3},
+		{"Мой_счёт = 1", 1, 8},
+		{"中文 = 1", 1, 4},
+		{"x = 1234;", 5, 4},
+		{"a = 1", 3, 1},
+		{"a", 5, 1},
+	}
+
+	for _, tt := range tests {
+		if got := tokenWidth(tt.line, tt.char); got != tt.want {
+			t.Errorf("tokenWidth(%q, %d) = %d, want %d", tt.line, tt.char, got, tt.want)
+		}
+	}
+}
+
+func TestSliceColumns(t *testing.T) {
+	tests := []struct {
+		line       string
+		start, end int
+		want       string
+	}{
+		{"abcdef", 1, 4, "bcd"},
+		{"a中b", 1, 3, "中"},
+		// a wide rune across the edge is left out rather than split
+		{"a中b", 2, 4, "b"},
+		{"Мой советник", 4, 12, "советник"},
+	}
+
+	for _, tt := range tests {
+		if got := sliceColumns(tt.line, tt.start, tt.end); got != tt.want {
+			t.Errorf("sliceColumns(%q, %d, %d) = %q, want %q", tt.line, tt.start, tt.end, got, tt.want)
+		}
+	}
+}
+
+func TestBuildSnippet(t *testing.T) {
+	source := []string{"void f()", "{", "\tint x = 1;", "\tfoo = 2;", "}"}
+	long := strings.Repeat("ab ", 30) + "foo;"
+
+	tests := []struct {
+		name    string
+		lines   []string
+		info    Info
+		context int
+		want    snippet
+	}{
+		{
+			name:  "tab indented",
+			lines: source,
+			info:  Info{Line: 4, Char: 2},
+			want:  snippet{lines: []string{" foo = 2;"}, first: 4, caretCol: 1, caretLen: 3},
+		},
+		{
+			name:    "context lines",
+			lines:   source,
+			info:    Info{Line: 4, Char: 2},
+			context: 1,
+			want:    snippet{lines: []string{"   int x = 1;", "   foo = 2;", "}"}, first: 3, caretCol: 3, caretLen: 3},
+		},
+		{
+			name:    "context at the start of the file",
+			lines:   source,
+			info:    Info{Line: 1, Char: 6},
+			context: 2,
+			want:    snippet{lines: []string{"void f()", "{", "   int x = 1;"}, first: 1, caretCol: 5, caretLen: 1},
+		},
+		{
+			name:  "cyrillic",
+			lines: []string{"\tМой = Счёт;"},
+			info:  Info{Line: 1, Char: 8},
+			want:  snippet{lines: []string{" Мой = Счёт;"}, first: 1, caretCol: 7, caretLen: 4},
+		},
+		{
+			name:  "cjk",
+			lines: []string{"x = 中文;"},
+			info:  Info{Line: 1, Char: 5},
+			want:  snippet{lines: []string{"x = 中文;"}, first: 1, caretCol: 4, caretLen: 4},
+		},
+		{
+			name:  "long line, first chunk",
+			lines: []string{long},
+			info:  Info{Line: 1, Char: 1},
+			want:  snippet{lines: []string{long[:snippetWidth]}, first: 1, caretCol: 0, caretLen: 2},
+		},
+		{
+			name:  "long line, last chunk",
+			lines: []string{long},
+			info:  Info{Line: 1, Char: 91},
+			want:  snippet{lines: []string{long[len(long)-snippetWidth:]}, first: 1, caretCol: 65, caretLen: 3},
+		},
+		{
+			name:  "crlf",
+			lines: []string{"int x = y;\r"},
+			info:  Info{Line: 1, Char: 9},
+			want:  snippet{lines: []string{"int x = y;"}, first: 1, caretCol: 8, caretLen: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := buildSnippet(tt.lines, tt.info, tt.context, snippetWidth)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("buildSnippet() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
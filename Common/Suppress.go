@@ -0,0 +1,142 @@
+package Common
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	catppuccin "github.com/catppuccin/go"
+	"github.com/charmbracelet/lipgloss"
+)
+
+var suppressRe = regexp.MustCompile(`(?i)//.*?\bgo-mql:ignore\s+(\d+(?:[\s,]+\d+)*)`)
+
+// A `// go-mql:ignore 43` comment, it hides the warnings with the code on the
+// line of the comment or, when the comment has a line of its own, the next one
+type Suppression struct {
+	File string
+	// where the comment is
+	Line int
+	// where the warnings are hidden
+	Target int
+	Code   int
+	used   bool
+}
+
+func findSuppressions(file string, source string) []Suppression {
+	var suppressions []Suppression
+
+	lines := strings.Split(source, "\n")
+	for i, line := range lines {
+		match := suppressRe.FindStringSubmatchIndex(line)
+		if match == nil {
+			continue
+		}
+
+		target := i + 1
+		if strings.TrimSpace(line[:match[0]]) == "" {
+			target = i + 2
+		}
+
+		codes := strings.FieldsFunc(line[match[2]:match[3]], func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t'
+		})
+		for _, code := range codes {
+			number, _ := strconv.Atoi(code)
+			suppressions = append(suppressions, Suppression{File: file, Line: i + 1, Target: target, Code: number})
+		}
+	}
+
+	return suppressions
+}
+
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
+
+// Hides the suppressed warnings of the target and the files it includes, and
+// returns the suppressions that hid nothing
+func ApplySuppressions(target string, diagnostics Diagnostic) (Diagnostic, []Suppression) {
+	files := collectIncludes(target)
+
+	// warnings can come from files the includes missed too
+	for _, info := range diagnostics.info {
+		if info.ScriptName != "" {
+			files = append(files, info.ScriptName)
+		}
+	}
+
+	var suppressions []Suppression
+	scanned := map[string]bool{}
+	for _, file := range files {
+		if scanned[absPath(file)] {
+			continue
+		}
+		scanned[absPath(file)] = true
+
+		source, _, err := ReadSource(file)
+		if err != nil {
+			continue
+		}
+		suppressions = append(suppressions, findSuppressions(file, string(source))...)
+	}
+
+	if len(suppressions) == 0 {
+		return diagnostics, nil
+	}
+
+	var infos []Info
+	for _, info := range diagnostics.info {
+		if info.Type == "warning" && suppress(suppressions, info) {
+			diagnostics.totalWarnings--
+			continue
+		}
+		infos = append(infos, info)
+	}
+	diagnostics.info = infos
+
+	var unused []Suppression
+	for _, suppression := range suppressions {
+		if !suppression.used {
+			unused = append(unused, suppression)
+		}
+	}
+
+	return diagnostics, unused
+}
+
+func suppress(suppressions []Suppression, info Info) bool {
+	file := absPath(info.ScriptName)
+
+	suppressed := false
+	for i := range suppressions {
+		s := &suppressions[i]
+		if s.Code == info.Code && s.Target == info.Line && absPath(s.File) == file {
+			s.used = true
+			suppressed = true
+		}
+	}
+
+	return suppressed
+}
+
+func PrintUnusedSuppressions(unused []Suppression) {
+	if len(unused) == 0 {
+		return
+	}
+
+	warnStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(catppuccin.Mocha.Yellow().Hex))
+
+	Logger.Warn("Unused suppressions", "Total", len(unused))
+	fmt.Println()
+	for _, suppression := range unused {
+		location := fmt.Sprintf("%s:%d", suppression.File, suppression.Line)
+		fmt.Printf("  %s %s no warning %d on line %d\n", warnStyle.Render("go-mql:ignore"), FaintStyle.Render(location), suppression.Code, suppression.Target)
+	}
+	fmt.Println()
+}
@@ -0,0 +1,151 @@
+package Common
+
+import (
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// functions, variables and constants of MQL4 that need no declaration
+var mqlBuiltins = []string{
+	"Alert", "Comment", "Print", "PrintFormat", "StringFormat", "GetLastError", "ResetLastError", "Sleep",
+	"OrderSend", "OrderSelect", "OrderClose", "OrderModify", "OrderDelete", "OrdersTotal", "OrdersHistoryTotal",
+	"OrderTicket", "OrderType", "OrderLots", "OrderOpenPrice", "OrderClosePrice", "OrderStopLoss",
+	"OrderTakeProfit", "OrderProfit", "OrderSwap", "OrderCommission", "OrderMagicNumber", "OrderSymbol",
+	"OrderComment", "OrderOpenTime", "OrderCloseTime", "OrderExpiration",
+	"AccountBalance", "AccountEquity", "AccountFreeMargin", "AccountMargin", "AccountProfit", "AccountNumber",
+	"AccountLeverage", "AccountCurrency", "AccountInfoDouble", "AccountInfoInteger", "AccountInfoString",
+	"MarketInfo", "SymbolInfoDouble", "SymbolInfoInteger", "SymbolInfoString", "Symbol", "Period",
+	"RefreshRates", "IsTradeAllowed", "IsTesting", "IsOptimization", "IsConnected", "IsStopped",
+	"NormalizeDouble", "MathAbs", "MathMax", "MathMin", "MathPow", "MathSqrt", "MathRound", "MathFloor",
+	"MathCeil", "MathLog", "MathExp", "MathRand", "MathSrand", "MathMod",
+	"DoubleToString", "IntegerToString", "StringToDouble", "StringToInteger", "TimeToString", "StringToTime",
+	"StringLen", "StringFind", "StringSubstr", "StringReplace", "StringSplit", "StringTrimLeft", "StringTrimRight",
+	"ArraySize", "ArrayResize", "ArrayInitialize", "ArraySetAsSeries", "ArrayCopy", "ArrayFree", "ArraySort",
+	"ArrayMaximum", "ArrayMinimum",
+	"TimeCurrent", "TimeLocal", "TimeGMT", "TimeHour", "TimeMinute", "TimeDayOfWeek", "Hour", "Minute", "DayOfWeek",
+	"iMA", "iRSI", "iMACD", "iATR", "iADX", "iBands", "iCCI", "iStochastic", "iCustom", "iClose", "iOpen",
+	"iHigh", "iLow", "iTime", "iVolume", "iBars", "iBarShift", "iHighest", "iLowest",
+	"ObjectCreate", "ObjectDelete", "ObjectSetInteger", "ObjectSetDouble", "ObjectSetString", "ObjectsDeleteAll",
+	"ChartRedraw", "SetIndexBuffer", "SetIndexStyle", "SetIndexLabel", "IndicatorBuffers", "IndicatorShortName",
+	"EventSetTimer", "EventKillTimer", "GlobalVariableGet", "GlobalVariableSet", "GlobalVariableCheck",
+	"FileOpen", "FileClose", "FileWrite", "FileReadString", "FileIsEnding",
+	"Ask", "Bid", "Point", "Digits", "Bars", "Open", "High", "Low", "Close", "Time", "Volume",
+	"OP_BUY", "OP_SELL", "OP_BUYLIMIT", "OP_SELLLIMIT", "OP_BUYSTOP", "OP_SELLSTOP",
+	"SELECT_BY_POS", "SELECT_BY_TICKET", "MODE_TRADES", "MODE_HISTORY",
+	"MODE_SMA", "MODE_EMA", "MODE_SMMA", "MODE_LWMA", "PRICE_CLOSE", "PRICE_OPEN", "PRICE_HIGH", "PRICE_LOW",
+	"PERIOD_CURRENT", "PERIOD_M1", "PERIOD_M5", "PERIOD_M15", "PERIOD_M30", "PERIOD_H1", "PERIOD_H4", "PERIOD_D1",
+	"clrNONE", "clrRed", "clrGreen", "clrBlue", "clrWhite", "clrBlack", "clrYellow",
+	"INIT_SUCCEEDED", "INIT_FAILED", "EMPTY_VALUE", "NULL", "true", "false",
+}
+
+var declarationRe = regexp.MustCompile(
+	`\b(?:void|bool|char|uchar|short|ushort|int|uint|long|ulong|float|double|string|datetime|color|class|struct|enum)\s+&?\s*([A-Za-z_]\w*)`,
+)
+
+var defineRe = regexp.MustCompile(`(?m)^\s*#define\s+([A-Za-z_]\w*)`)
+
+// Names declared in the sources: variables, functions, types and #defines
+func declaredSymbols(source string) []string {
+	var symbols []string
+	for _, match := range declarationRe.FindAllStringSubmatch(source, -1) {
+		symbols = append(symbols, match[1])
+	}
+	for _, match := range defineRe.FindAllStringSubmatch(source, -1) {
+		symbols = append(symbols, match[1])
+	}
+	return symbols
+}
+
+// Every symbol the target can see, the built-ins, its includes and the headers
+// of the workspace
+func symbolIndex(target string) []string {
+	files := collectIncludes(target)
+
+	filepath.WalkDir(".", func(path string, entry fs.DirEntry, err error) error {
+		if err == nil && !entry.IsDir() && strings.EqualFold(filepath.Ext(path), ".mqh") {
+			files = append(files, path)
+		}
+		return nil
+	})
+
+	seen := map[string]bool{}
+	var symbols []string
+	add := func(symbol string) {
+		if !seen[symbol] {
+			seen[symbol] = true
+			symbols = append(symbols, symbol)
+		}
+	}
+
+	for _, builtin := range mqlBuiltins {
+		add(builtin)
+	}
+
+	for _, file := range files {
+		source, _, err := ReadSource(file)
+		if err != nil {
+			continue
+		}
+		for _, symbol := range declaredSymbols(string(source)) {
+			add(symbol)
+		}
+	}
+
+	sort.Strings(symbols)
+	return symbols
+}
+
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+
+	return previous[len(rb)]
+}
+
+// The known symbol closest to the name, a different case wins over typos
+func closestSymbol(name string, symbols []string) (string, bool) {
+	best, bestDistance := "", -1
+
+	// short names have few letters to spare
+	limit := 1
+	if len([]rune(name)) > 5 {
+		limit = 2
+	}
+
+	for _, symbol := range symbols {
+		if symbol == name {
+			continue
+		}
+
+		distance := levenshtein(strings.ToLower(name), strings.ToLower(symbol))
+		if distance == 0 {
+			return symbol, true
+		}
+
+		if distance <= limit && (bestDistance < 0 || distance < bestDistance) {
+			best, bestDistance = symbol, distance
+		}
+	}
+
+	return best, bestDistance >= 0
+}
@@ -3,29 +3,20 @@ package Common
 import (
 	"fmt"
 	"os"
-	"os/exec"
-	"runtime"
 	"time"
-
-	"github.com/charmbracelet/huh/spinner"
-	"github.com/charmbracelet/lipgloss"
-	"golang.org/x/exp/rand"
 )
 
-func syntaxMetaEditor(target, logfile string, cfg *MQLConfig) (outputStr string, status int) {
-	// MT4 should be on portable mode
-
-	// for linux and mac
-
-	cmd := exec.Command("wine", cfg.MetaEditorPath, "/compile:"+target, "/log:"+logfile, "/s")
+func syntaxMetaEditor(target, logfile string, cfg *MQLConfig, timings *Timings) (outputStr string, status int) {
+	cmd := metaEditorCommand(cfg, target, logfile, "/s")
 
-	if runtime.GOOS == "windows" {
-		cmd = exec.Command(cfg.MetaEditorPath, "/compile:"+target, "/log:"+logfile, "/s")
-	}
+	start := time.Now()
 
 	// check the status of the command
 	cmd.Run()
 
+	timings.Run = time.Since(start)
+	start = time.Now()
+
 	// read the log file
 	logFile, err := os.ReadFile(logfile)
 	if err != nil {
@@ -37,39 +28,19 @@ func syntaxMetaEditor(target, logfile string, cfg *MQLConfig) (outputStr string,
 		return "", 1
 	}
 
+	timings.Decode = time.Since(start)
+
 	return logFileUTF8, 0
 }
 
-func SyntaxCheck(target string, logfile string, compileTarget map[string]string, cfg *MQLConfig) (outputStr string, status int) {
+func SyntaxCheck(target string, logfile string, compileTarget map[string]string, cfg *MQLConfig, timings *Timings) (outputStr string, status int) {
 	fmt.Println()
 	Logger.Info("Checking syntax", Keyvals(compileTarget)...)
 	fmt.Println()
 
-	rand.Seed(uint64(time.Now().Nanosecond()))
-	randomSpinner := Spinners[rand.Intn(len(Spinners))]
-
-	// var outputStr string
-	// var status int
-
-	runCompileCmd := func() {
-		outputStr, status = syntaxMetaEditor(target, logfile, cfg)
-	}
-	err := spinner.New().
-		Type(randomSpinner).
-		Title(SpinnerStyle.
-			Render(
-				fmt.Sprintf(
-					"Checking syntax %s",
-					SpinnerTitleStyle.Render(target),
-				),
-			),
-		).
-		Style(lipgloss.NewStyle().Foreground(lipgloss.Color("#F780E2")).PaddingLeft(1)).
-		Action(runCompileCmd).
-		Run()
-	if err != nil {
-		fmt.Println(err)
-	}
+	RunWithSpinner("Checking syntax", target, func() {
+		outputStr, status = syntaxMetaEditor(target, logfile, cfg, timings)
+	})
 
 	fmt.Println()
 
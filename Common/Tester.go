@@ -0,0 +1,197 @@
+package Common
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+type TesterSummary struct {
+	NetProfit    string
+	Drawdown     string
+	TotalTrades  string
+	ProfitFactor string
+}
+
+// labels of the report rows we care about, mapped to the summary fields
+var testerReportLabels = map[string]func(s *TesterSummary, v string){
+	"total net profit": func(s *TesterSummary, v string) { s.NetProfit = v },
+	"maximal drawdown": func(s *TesterSummary, v string) { s.Drawdown = v },
+	"total trades":     func(s *TesterSummary, v string) { s.TotalTrades = v },
+	"profit factor":    func(s *TesterSummary, v string) { s.ProfitFactor = v },
+}
+
+var (
+	reportCellRe = regexp.MustCompile(`(?is)<(td|Data)[^>]*>(.*?)</(td|Data)>`)
+	reportTagRe  = regexp.MustCompile(`(?s)<[^>]*>`)
+)
+
+// The expert as the tester knows it, relative to MQL4\Experts and without the
+// extension (Examples\MACD Sample)
+func testerExpert(target string) string {
+	expert := strings.TrimSuffix(target, filepath.Ext(target))
+
+	rel, err := filepath.Rel(absPath("Experts"), absPath(expert))
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		// not in the Experts folder of the cwd, the tester can only find it by name
+		return filepath.Base(expert)
+	}
+
+	return strings.ReplaceAll(rel, string(filepath.Separator), "\\")
+}
+
+// Writes the tester.ini the terminal reads through /config: and returns its path
+func WriteTesterConfig(target string, cfg *MQLConfig) (string, error) {
+	report := cfg.TesterReport
+	if filepath.IsAbs(report) {
+		report = ToWinePath(report)
+	}
+
+	lines := []string{
+		"TestExpert=" + testerExpert(target),
+		"TestSymbol=" + cfg.TesterSymbol,
+		"TestPeriod=" + cfg.TesterPeriod,
+		fmt.Sprintf("TestModel=%d", cfg.TesterModel),
+		"TestOptimization=false",
+		"TestReport=" + report,
+		"TestReplaceReport=true",
+		"TestShutdownTerminal=true",
+	}
+
+	if cfg.TesterSet != "" {
+		lines = append(lines, "TestExpertParameters="+cfg.TesterSet)
+	}
+
+	if cfg.TesterFrom != "" || cfg.TesterTo != "" {
+		lines = append(lines,
+			"TestDateEnable=true",
+			"TestFromDate="+cfg.TesterFrom,
+			"TestToDate="+cfg.TesterTo,
+		)
+	}
+
+	terminalDir := filepath.Dir(cfg.TerminalPath)
+	configPath := filepath.Join(terminalDir, "tester.ini")
+
+	err := os.WriteFile(configPath, []byte(strings.Join(lines, "\r\n")+"\r\n"), 0o644)
+	if err != nil {
+		return "", err
+	}
+
+	return configPath, nil
+}
+
+// Path of the report the terminal writes, relative to the terminal directory
+func testerReportPath(cfg *MQLConfig) string {
+	report := cfg.TesterReport
+	if filepath.Ext(report) == "" {
+		report += ".htm"
+	}
+
+	if filepath.IsAbs(report) {
+		return report
+	}
+
+	return filepath.Join(filepath.Dir(cfg.TerminalPath), report)
+}
+
+func runTerminal(cfg *MQLConfig) error {
+	terminalPath, err := filepath.Abs(cfg.TerminalPath)
+	if err != nil {
+		return err
+	}
+
+	// MT4 should be on portable mode
+
+	cmd := wineCommand(cfg, terminalPath, "/portable", "/config:tester.ini")
+
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command(terminalPath, "/portable", "/config:tester.ini")
+	}
+
+	// the config and the report are resolved from the terminal directory
+	cmd.Dir = filepath.Dir(terminalPath)
+
+	return cmd.Run()
+}
+
+// Extracts the summary rows from an HTML (MT4) or XML spreadsheet (MT5) report
+func ParseTesterReport(report string) TesterSummary {
+	var summary TesterSummary
+
+	var cells []string
+	for _, match := range reportCellRe.FindAllStringSubmatch(report, -1) {
+		cell := strings.TrimSpace(reportTagRe.ReplaceAllString(match[2], ""))
+		cell = strings.ReplaceAll(cell, "&nbsp;", " ")
+		if cell != "" {
+			cells = append(cells, cell)
+		}
+	}
+
+	for i := 0; i < len(cells)-1; i++ {
+		label := strings.ToLower(strings.TrimSuffix(cells[i], ":"))
+		if setField, ok := testerReportLabels[label]; ok {
+			setField(&summary, cells[i+1])
+		}
+	}
+
+	return summary
+}
+
+// Backtests the target and prints the summary of the report
+func RunTester(target string, cfg *MQLConfig) (TesterSummary, error) {
+	var summary TesterSummary
+
+	fmt.Println()
+	Logger.Info("Backtesting",
+		"target", target,
+		"Symbol", cfg.TesterSymbol,
+		"Period", cfg.TesterPeriod,
+	)
+	fmt.Println()
+
+	if _, err := WriteTesterConfig(target, cfg); err != nil {
+		return summary, err
+	}
+
+	reportPath := testerReportPath(cfg)
+	os.Remove(reportPath)
+
+	var runErr error
+	RunWithSpinner("Backtesting", target, func() {
+		runErr = runTerminal(cfg)
+	})
+
+	if runErr != nil {
+		return summary, fmt.Errorf("Failed to run the terminal: %w", runErr)
+	}
+
+	report, err := os.ReadFile(reportPath)
+	if err != nil {
+		return summary, fmt.Errorf("No tester report found at %s", reportPath)
+	}
+
+	// MT5 writes its reports as UTF-16
+	reportStr := string(report)
+	if len(report) > 1 && report[0] == 0xff && report[1] == 0xfe {
+		reportStr, _ = DecodeUTF16(report[2:])
+	}
+
+	summary = ParseTesterReport(reportStr)
+
+	fmt.Println()
+	Logger.Info("Backtest",
+		"Net profit", summary.NetProfit,
+		"Drawdown", summary.Drawdown,
+		"Trades", summary.TotalTrades,
+		"Profit factor", summary.ProfitFactor,
+	)
+	fmt.Println()
+	fmt.Println("Report is saved in", Bold.Render(reportPath))
+
+	return summary, nil
+}
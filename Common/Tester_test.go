@@ -0,0 +1,124 @@
+package Common
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// Runs the test from a fresh MQL4 folder inside the terminal folder
+func chdirTemp(t *testing.T) string {
+	t.Helper()
+
+	terminalDir := t.TempDir()
+	mql4 := filepath.Join(terminalDir, "MQL4")
+	if err := os.MkdirAll(filepath.Join(mql4, "Experts", "Examples"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(mql4); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(cwd) })
+
+	return terminalDir
+}
+
+func TestTesterExpert(t *testing.T) {
+	chdirTemp(t)
+
+	tests := []struct {
+		target string
+		want   string
+	}{
+		{"Experts/ea.mq4", "ea"},
+		{"Experts/Examples/MACD Sample.mq4", `Examples\MACD Sample`},
+		{"./Experts/Examples/Мой советник.mq4", `Examples\Мой советник`},
+		{"../elsewhere/ea.mq4", "ea"},
+	}
+
+	for _, tt := range tests {
+		if got := testerExpert(tt.target); got != tt.want {
+			t.Errorf("testerExpert(%q) = %q, want %q", tt.target, got, tt.want)
+		}
+	}
+}
+
+// A stand-in for wine that runs the "terminal" as a shell script
+func writeFakeTerminal(t *testing.T, dir, script string) string {
+	t.Helper()
+
+	path := filepath.Join(dir, "fake-wine")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRunTester(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake terminal is a shell script")
+	}
+
+	PlainOutput = true
+
+	report := `<table><tr><td>Total net profit</td><td>100.00</td></tr>` +
+		`<tr><td>Maximal drawdown</td><td>5.00</td></tr>` +
+		`<tr><td>Total trades</td><td>7</td></tr></table>`
+
+	tests := []struct {
+		name    string
+		script  string
+		wantErr string
+	}{
+		{"report", "printf '%s' '" + report + "' > tester_report.htm\n", ""},
+		{"terminal fails", "exit 3\n", "Failed to run the terminal"},
+		{"no report", "exit 0\n", "No tester report"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			terminalDir := chdirTemp(t)
+
+			cfg := &MQLConfig{
+				Wine:         writeFakeTerminal(t, t.TempDir(), tt.script),
+				TerminalPath: filepath.Join(terminalDir, "terminal.exe"),
+				TesterSymbol: "EURUSD",
+				TesterPeriod: "H1",
+				TesterReport: "tester_report",
+			}
+
+			summary, err := RunTester("Experts/Examples/MACD Sample.mq4", cfg)
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("RunTester() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("RunTester() error = %v", err)
+			}
+
+			want := TesterSummary{NetProfit: "100.00", Drawdown: "5.00", TotalTrades: "7"}
+			if summary != want {
+				t.Errorf("RunTester() = %+v, want %+v", summary, want)
+			}
+
+			ini, err := os.ReadFile(filepath.Join(terminalDir, "tester.ini"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(ini), "TestExpert=Examples\\MACD Sample\r\n") {
+				t.Errorf("tester.ini doesn't name the expert by its path:\n%s", ini)
+			}
+		})
+	}
+}
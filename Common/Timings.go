@@ -0,0 +1,159 @@
+package Common
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/lipgloss/table"
+)
+
+// Every --timings build is appended to it as one JSON line, in the workspace's
+// cache folder
+const TimingsFile = "timings.jsonl"
+
+// Wall-clock of each phase of a build
+type Timings struct {
+	Target string
+	Mode   string
+	Time   time.Time
+	// wine and metaeditor together, from start to exit
+	Run time.Duration
+	// what metaeditor reports in its log
+	MetaEditor time.Duration
+	Decode     time.Duration
+	Parse      time.Duration
+	Render     time.Duration
+	Total      time.Duration
+}
+
+type timingsRecord struct {
+	Target       string    `json:"target"`
+	Mode         string    `json:"mode"`
+	Time         time.Time `json:"time"`
+	WineStartup  int64     `json:"wine_startup_ms"`
+	MetaEditor   int64     `json:"metaeditor_ms"`
+	Run          int64     `json:"run_ms"`
+	Decode       int64     `json:"decode_ms"`
+	Parse        int64     `json:"parse_ms"`
+	Render       int64     `json:"render_ms"`
+	ToolOverhead int64     `json:"tool_overhead_ms"`
+	Total        int64     `json:"total_ms"`
+}
+
+// Time spent starting wine, the part of the run metaeditor doesn't account for
+func (t Timings) WineStartup() time.Duration {
+	if t.Run < t.MetaEditor {
+		return 0
+	}
+	return t.Run - t.MetaEditor
+}
+
+// Time the tool spends around metaeditor
+func (t Timings) ToolOverhead() time.Duration {
+	if t.Total < t.Run {
+		return 0
+	}
+	return t.Total - t.Run
+}
+
+func (t Timings) record() timingsRecord {
+	return timingsRecord{
+		Target:       t.Target,
+		Mode:         t.Mode,
+		Time:         t.Time,
+		WineStartup:  t.WineStartup().Milliseconds(),
+		MetaEditor:   t.MetaEditor.Milliseconds(),
+		Run:          t.Run.Milliseconds(),
+		Decode:       t.Decode.Milliseconds(),
+		Parse:        t.Parse.Milliseconds(),
+		Render:       t.Render.Milliseconds(),
+		ToolOverhead: t.ToolOverhead().Milliseconds(),
+		Total:        t.Total.Milliseconds(),
+	}
+}
+
+func PrintTimings(t Timings) {
+	rows := [][2]string{
+		{"Wine startup", strconv.FormatInt(t.WineStartup().Milliseconds(), 10)},
+		{"MetaEditor", strconv.FormatInt(t.MetaEditor.Milliseconds(), 10)},
+		{"Log decode", strconv.FormatInt(t.Decode.Milliseconds(), 10)},
+		{"Parse", strconv.FormatInt(t.Parse.Milliseconds(), 10)},
+		{"Render", strconv.FormatInt(t.Render.Milliseconds(), 10)},
+		{"Tool overhead", strconv.FormatInt(t.ToolOverhead().Milliseconds(), 10)},
+		{"Total", strconv.FormatInt(t.Total.Milliseconds(), 10)},
+	}
+
+	fmt.Println()
+
+	if PlainOutput {
+		fmt.Printf("==> Timings of %s\n\n", t.Target)
+		for _, row := range rows {
+			fmt.Printf("%-15s %8s ms\n", row[0], row[1])
+		}
+		fmt.Println()
+		return
+	}
+
+	tbl := table.New().
+		Border(lipgloss.RoundedBorder()).
+		BorderStyle(FaintStyle).
+		StyleFunc(func(row, col int) lipgloss.Style {
+			style := lipgloss.NewStyle().Padding(0, 1)
+			if col > 0 {
+				style = style.Align(lipgloss.Right)
+			}
+			// row 0 is the header, the total is the last row
+			if row == len(rows) {
+				style = style.Bold(true)
+			}
+			return style
+		}).
+		Headers(t.Target, "ms")
+
+	for _, row := range rows {
+		tbl.Row(row[0], row[1])
+	}
+
+	fmt.Println(tbl.Render())
+	fmt.Println()
+}
+
+// The timings of the workspace
+func TimingsPath() (string, error) {
+	dir, err := workspaceCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, TimingsFile), nil
+}
+
+// Appends the timings to the timings file
+func SaveTimings(t Timings) error {
+	line, err := json.Marshal(t.record())
+	if err != nil {
+		return err
+	}
+
+	path, err := TimingsPath()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.Write(append(line, '\n'))
+	return err
+}
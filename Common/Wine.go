@@ -0,0 +1,40 @@
+package Common
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Command running the windows program through the configured wine, which can be
+// a binary (wine, wine64), a wrapper script or something like "proton run"
+func wineCommand(cfg *MQLConfig, program string, args ...string) *exec.Cmd {
+	wine := strings.Fields(cfg.Wine)
+	if len(wine) == 0 {
+		wine = []string{"wine"}
+	}
+
+	cmdArgs := append(wine[1:], program)
+	cmdArgs = append(cmdArgs, args...)
+
+	cmd := exec.Command(wine[0], cmdArgs...)
+	cmd.Env = wineEnv(cfg)
+
+	return cmd
+}
+
+// The environment with the wine prefix, debug channels and the extra variables
+func wineEnv(cfg *MQLConfig) []string {
+	env := os.Environ()
+
+	if cfg.WinePrefix != "" {
+		env = append(env, "WINEPREFIX="+cfg.WinePrefix)
+	}
+
+	// wine floods stderr with fixme messages by default
+	if cfg.WineDebug != "" {
+		env = append(env, "WINEDEBUG="+cfg.WineDebug)
+	}
+
+	return append(env, cfg.WineEnv...)
+}
@@ -0,0 +1,175 @@
+package Common
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// A wine drive letter and the Linux folder it points to
+type DriveMapping struct {
+	Drive string
+	Path  string
+}
+
+var driveNameRe = regexp.MustCompile(`^[a-z]:$`)
+
+// drives known to the wine prefix, longest path first
+var wineDrives []DriveMapping
+
+func winePrefix(cfg *MQLConfig) string {
+	if cfg.WinePrefix != "" {
+		return cfg.WinePrefix
+	}
+
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".wine")
+}
+
+// Reads the drives the way winepath does, from the dosdevices symlinks of the prefix
+func readDosDevices(prefix string) []DriveMapping {
+	dosdevices := filepath.Join(prefix, "dosdevices")
+
+	entries, err := os.ReadDir(dosdevices)
+	if err != nil {
+		// a fresh prefix maps these two
+		return []DriveMapping{
+			{Drive: "c", Path: filepath.Join(prefix, "drive_c")},
+			{Drive: "z", Path: "/"},
+		}
+	}
+
+	var drives []DriveMapping
+	for _, entry := range entries {
+		if !driveNameRe.MatchString(entry.Name()) {
+			continue
+		}
+
+		target, err := os.Readlink(filepath.Join(dosdevices, entry.Name()))
+		if err != nil {
+			continue
+		}
+
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(dosdevices, target)
+		}
+
+		drives = append(drives, DriveMapping{Drive: entry.Name()[:1], Path: filepath.Clean(target)})
+	}
+
+	return drives
+}
+
+// Loads the drive mappings of the wine prefix, the configured ones (X:=/path) win
+func InitWinePaths(cfg *MQLConfig) {
+	drives := map[string]string{}
+
+	for _, drive := range readDosDevices(winePrefix(cfg)) {
+		drives[drive.Drive] = drive.Path
+	}
+
+	for _, mapping := range cfg.WineDrives {
+		drive, path, ok := strings.Cut(mapping, "=")
+		drive = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(drive), ":"))
+		if !ok || len(drive) != 1 {
+			Logger.Warn("Ignoring wine drive mapping", "mapping", mapping)
+			continue
+		}
+		drives[drive] = filepath.Clean(path)
+	}
+
+	wineDrives = nil
+	for drive, path := range drives {
+		// resolve symlinks so that prefixes compare with resolved paths
+		if resolved, err := filepath.EvalSymlinks(path); err == nil {
+			path = resolved
+		}
+		wineDrives = append(wineDrives, DriveMapping{Drive: drive, Path: path})
+	}
+
+	sort.Slice(wineDrives, func(i, j int) bool {
+		if len(wineDrives[i].Path) != len(wineDrives[j].Path) {
+			return len(wineDrives[i].Path) > len(wineDrives[j].Path)
+		}
+		return wineDrives[i].Drive < wineDrives[j].Drive
+	})
+}
+
+// Resolves symlinks of the deepest folder of the path that exists
+func resolvePath(path string) string {
+	dir, rest := path, ""
+	for dir != "/" && dir != "." {
+		if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+			return filepath.Join(resolved, rest)
+		}
+		rest = filepath.Join(filepath.Base(dir), rest)
+		dir = filepath.Dir(dir)
+	}
+	return path
+}
+
+// Converts a Linux path into the Windows path metaeditor sees under wine
+func ToWinePath(path string) string {
+	if runtime.GOOS == "windows" {
+		return path
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	abs = resolvePath(abs)
+
+	for _, drive := range wineDrives {
+		rel, err := filepath.Rel(drive.Path, abs)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, "../") {
+			continue
+		}
+
+		if rel == "." {
+			rel = ""
+		}
+
+		return strings.ToUpper(drive.Drive) + ":\\" + strings.ReplaceAll(rel, "/", "\\")
+	}
+
+	// without a z: drive there is no way to reach the file
+	return path
+}
+
+// Maps a path from a metaeditor log back to a Linux path, relative to the cwd
+// when it's inside of it
+func FromWinePath(path string) string {
+	if runtime.GOOS == "windows" {
+		return path
+	}
+
+	if len(path) < 2 || path[1] != ':' {
+		return strings.ReplaceAll(path, "\\", "/")
+	}
+
+	drive := strings.ToLower(path[:1])
+	rest := strings.TrimPrefix(strings.ReplaceAll(path[2:], "\\", "/"), "/")
+
+	for _, mapping := range wineDrives {
+		if mapping.Drive != drive {
+			continue
+		}
+
+		linuxPath := filepath.Join(mapping.Path, rest)
+
+		if cwd, err := os.Getwd(); err == nil {
+			cwd = resolvePath(cwd)
+			if rel, err := filepath.Rel(cwd, linuxPath); err == nil && rel != ".." && !strings.HasPrefix(rel, "../") {
+				return rel
+			}
+		}
+
+		return linuxPath
+	}
+
+	return strings.ReplaceAll(path, "\\", "/")
+}
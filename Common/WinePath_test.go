@@ -0,0 +1,95 @@
+package Common
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// Maps c: to a drive_c folder in a temp dir and z: to /
+func fakeWineDrives(t *testing.T) string {
+	t.Helper()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("paths aren't translated on windows")
+	}
+
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	saved := wineDrives
+	wineDrives = []DriveMapping{
+		{Drive: "c", Path: filepath.Join(dir, "drive_c")},
+		{Drive: "z", Path: "/"},
+	}
+	t.Cleanup(func() { wineDrives = saved })
+
+	return dir
+}
+
+func TestToWinePath(t *testing.T) {
+	dir := fakeWineDrives(t)
+	driveC := filepath.Join(dir, "drive_c")
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{filepath.Join(driveC, "Program Files", "MT4", "MQL4", "Experts", "ea.mq4"), `C:\Program Files\MT4\MQL4\Experts\ea.mq4`},
+		{filepath.Join(driveC, "MT4", "Experts", "Мой советник.mq4"), `C:\MT4\Experts\Мой советник.mq4`},
+		{filepath.Join(driveC, "MT4", "Experts", "目标 策略", "ea 1.mq4"), `C:\MT4\Experts\目标 策略\ea 1.mq4`},
+		{driveC, `C:\`},
+		{"/opt/mt4/My Experts/ea.mq4", `Z:\opt\mt4\My Experts\ea.mq4`},
+		{"/opt/mt4/Эксперты/ea.mq4", `Z:\opt\mt4\Эксперты\ea.mq4`},
+	}
+
+	for _, tt := range tests {
+		if got := ToWinePath(tt.path); got != tt.want {
+			t.Errorf("ToWinePath(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestFromWinePath(t *testing.T) {
+	dir := fakeWineDrives(t)
+	driveC := filepath.Join(dir, "drive_c")
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{`C:\Program Files\MT4\MQL4\Include\stdlib.mqh`, filepath.Join(driveC, "Program Files", "MT4", "MQL4", "Include", "stdlib.mqh")},
+		{`c:\MT4\Experts\Мой советник.mq4`, filepath.Join(driveC, "MT4", "Experts", "Мой советник.mq4")},
+		{`Z:\opt\mt4\目标 策略\ea 1.mq4`, "/opt/mt4/目标 策略/ea 1.mq4"},
+		{`Experts\ea.mq4`, "Experts/ea.mq4"},
+		// unknown drives keep the path
+		{`D:\ea.mq4`, "D:/ea.mq4"},
+	}
+
+	for _, tt := range tests {
+		if got := FromWinePath(tt.path); got != tt.want {
+			t.Errorf("FromWinePath(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestFromWinePathRelativeToCwd(t *testing.T) {
+	dir := fakeWineDrives(t)
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(cwd) })
+
+	path := filepath.Join(dir, "Experts", "Мой советник.mq4")
+	if got := FromWinePath(ToWinePath(path)); got != filepath.Join("Experts", "Мой советник.mq4") {
+		t.Errorf("FromWinePath(ToWinePath(%q)) = %q", path, got)
+	}
+}
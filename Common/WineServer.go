@@ -0,0 +1,155 @@
+package Common
+
+import (
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A persistent wineserver kept alive for the session so that every metaeditor
+// run skips the wineserver startup
+type WineServer struct {
+	cfg     *MQLConfig
+	binary  string
+	owned   bool
+	warmup  time.Duration
+	warming sync.WaitGroup
+	stop    sync.Once
+
+	// wine startup of the builds that ran on the warm server
+	mu      sync.Mutex
+	builds  int
+	startup time.Duration
+}
+
+// wineserver next to the wine binary, or the one in $PATH
+func wineServerBinary(cfg *MQLConfig) string {
+	wine := strings.Fields(cfg.Wine)
+	if len(wine) > 0 {
+		if winePath, err := exec.LookPath(wine[0]); err == nil {
+			sibling := filepath.Join(filepath.Dir(winePath), "wineserver")
+			if _, err := exec.LookPath(sibling); err == nil {
+				return sibling
+			}
+		}
+	}
+	return "wineserver"
+}
+
+// Starts a persistent wineserver and warms the prefix up in the background,
+// a server that is already running is reused and left alone
+func StartWineServer(cfg *MQLConfig) *WineServer {
+	ws := &WineServer{cfg: cfg, binary: wineServerBinary(cfg)}
+
+	if runtime.GOOS == "windows" {
+		return ws
+	}
+
+	if wineServerRunning(winePrefix(cfg)) {
+		return ws
+	}
+
+	start := time.Now()
+
+	// -p without a delay keeps it alive until it's killed, it forks into the
+	// background so this returns once the server is up
+	server := exec.Command(ws.binary, "-p")
+	server.Env = wineEnv(cfg)
+	if err := server.Run(); err != nil {
+		Logger.Warn("Failed to start the wineserver", "err", err)
+		return ws
+	}
+	ws.owned = true
+
+	ws.warming.Add(1)
+	go func() {
+		defer ws.warming.Done()
+
+		// the first client boots the prefix services, which is the slow part
+		runWithTimeout(wineCommand(cfg, "cmd", "/c", "exit"), 2*time.Minute)
+
+		ws.warmup = time.Since(start)
+	}()
+
+	return ws
+}
+
+// Waits for the warm-up to finish, builds started before it would race the
+// prefix boot and pay the cold start anyway
+func (ws *WineServer) Wait() {
+	if ws == nil {
+		return
+	}
+	ws.warming.Wait()
+}
+
+// Counts the wine startup of a build towards the savings
+func (ws *WineServer) Observe(t Timings) {
+	if ws == nil {
+		return
+	}
+
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	// without a server of its own the build paid the usual startup
+	if !ws.owned {
+		return
+	}
+
+	ws.builds++
+	ws.startup += t.WineStartup()
+}
+
+// Shuts down the wineserver if this session started it, safe to call from the
+// signal handler and the deferred call at once
+func (ws *WineServer) Stop() {
+	if ws == nil {
+		return
+	}
+
+	ws.stop.Do(func() {
+		ws.Wait()
+
+		if !ws.owned {
+			return
+		}
+
+		stop := exec.Command(ws.binary, "-k")
+		stop.Env = wineEnv(ws.cfg)
+		stop.Run()
+
+		ws.mu.Lock()
+		ws.owned = false
+		ws.mu.Unlock()
+
+		ws.printSavings()
+	})
+}
+
+// Compares the cold start the warm-up paid once with the startup of the builds
+// that followed, every one of them would have paid the cold start without it
+func (ws *WineServer) printSavings() {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	coldStart := ws.warmup.Round(time.Millisecond)
+
+	if ws.builds == 0 {
+		Logger.Info("Wine server stopped", "cold start", coldStart)
+		return
+	}
+
+	warmStartup := (ws.startup / time.Duration(ws.builds)).Round(time.Millisecond)
+	saved := max(time.Duration(ws.builds)*(ws.warmup-warmStartup)-ws.warmup, 0).Round(time.Millisecond)
+
+	Logger.Info("Wine server stopped",
+		"cold start", coldStart,
+		"warm startup", warmStartup,
+		"builds", ws.builds,
+		"saved", saved,
+	)
+}
@@ -0,0 +1,10 @@
+//go:build !windows
+
+package Common
+
+import "os/exec"
+
+// Only windows builds a command line out of the arguments
+func setRawCommandLine(cmd *exec.Cmd, program string, args []string) {
+	cmd.Args = append([]string{program}, args...)
+}
@@ -0,0 +1,16 @@
+//go:build windows
+
+package Common
+
+import (
+	"os/exec"
+	"strings"
+	"syscall"
+)
+
+// Passes the arguments as they are, exec would quote the whole /compile:"..." switch
+func setRawCommandLine(cmd *exec.Cmd, program string, args []string) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		CmdLine: syscall.EscapeArg(program) + " " + strings.Join(args, " "),
+	}
+}
@@ -31,6 +31,15 @@ func helpView() string {
 		// "ctrl+u/d Jump 5 files up/down  • " +
 		// "ctrl+up/down Jump to first/last file  • " +
 		"ctrl+c/q Exit program"
+	// nerd font glyphs need a proper terminal
+	if PlainOutput {
+		return lipgloss.JoinHorizontal(
+			lipgloss.Top,
+			"GO MQL BUILD ",
+			helpStyle(helpViewStr),
+		)
+	}
+
 	return lipgloss.JoinHorizontal(
 		lipgloss.Top,
 		HighlightStyleFg(LEFT_HALF_CIRCLE),
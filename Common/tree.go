@@ -36,9 +36,15 @@ func (f *FileTreeNode) GenerateTree(t *tree.Tree) {
 			prefix = " "
 		}
 
+		if PlainOutput {
+			prefix = ""
+		}
+
 		fileName := prefix + key
 
-		if child.Selected {
+		if child.Selected && PlainOutput {
+			fileName = "> " + key
+		} else if child.Selected {
 			key = lipgloss.JoinHorizontal(
 				lipgloss.Left,
 				lipgloss.NewStyle().Foreground(lipgloss.Color("#2f334d")).Render(LEFT_HALF_CIRCLE),
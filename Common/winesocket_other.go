@@ -0,0 +1,34 @@
+//go:build !windows
+
+package Common
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// Whether a wineserver runs for the prefix, wine keeps its socket in a folder
+// named after the device and inode of the prefix
+func wineServerRunning(prefix string) bool {
+	stat, err := os.Stat(prefix)
+	if err != nil {
+		return false
+	}
+
+	sys, ok := stat.Sys().(*syscall.Stat_t)
+	if !ok {
+		return false
+	}
+
+	socket := filepath.Join(
+		os.TempDir(),
+		fmt.Sprintf(".wine-%d", os.Getuid()),
+		fmt.Sprintf("server-%x-%x", uint64(sys.Dev), uint64(sys.Ino)),
+		"socket",
+	)
+
+	_, err = os.Stat(socket)
+	return err == nil
+}
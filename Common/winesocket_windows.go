@@ -0,0 +1,8 @@
+//go:build windows
+
+package Common
+
+// There is no wineserver on windows
+func wineServerRunning(prefix string) bool {
+	return false
+}
@@ -5,35 +5,176 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
 	common "github.com/MAK227/go-mql-build/Common"
 	catppuccin "github.com/catppuccin/go"
 	tea "github.com/charmbracelet/bubbletea/v2"
 	"github.com/charmbracelet/lipgloss"
 	flag "github.com/spf13/pflag"
+	"golang.org/x/term"
 )
 
 var readFileCache map[string][]string
 
-func runBuild(mode string, target string, cfg *common.MQLConfig) {
-	compileTarget, logfile := common.BuildCompileTarget(target)
+// the --wineserver of the session, nil without it
+var wineServer *common.WineServer
+
+// Builds the target and returns the compiled artifact and the diagnostics with
+// whether the build succeeded
+func runBuild(mode string, target string, cfg *common.MQLConfig) (common.Artifact, common.Diagnostic, bool) {
+	compileTarget, logfile := common.BuildCompileTarget(target, cfg)
 
 	var outputStr string
 	status := 0
 
+	var artifact common.Artifact
+	var artifactErr error
+	var diagnostics common.Diagnostic
+
+	// metaeditor builds a wrapper that sets the defines and includes the target
+	buildTarget := target
+	if len(cfg.Defines) > 0 {
+		wrapper, err := common.WriteDefinesWrapper(target, cfg.Defines)
+		if err != nil {
+			common.PrintError(err)
+			return artifact, diagnostics, false
+		}
+
+		buildTarget = wrapper
+		compileTarget["Defines"] = strings.Join(cfg.Defines, " ")
+	}
+
+	// a build next to the warm-up would pay the cold start
+	wineServer.Wait()
+
+	start := time.Now()
+	timings := &common.Timings{Target: target, Mode: mode, Time: start}
+
 	switch mode {
 	case "compile":
-		outputStr, status = common.Compile(target, logfile, compileTarget, cfg)
+		outputStr, status = common.Compile(buildTarget, logfile, compileTarget, cfg, timings)
+
+		if buildTarget != target {
+			common.CleanupDefinesWrapper(target, buildTarget)
+		}
+
+		// a clean log doesn't mean metaeditor managed to write the .ex4
+		artifact, artifactErr = common.VerifyArtifact(target, start)
+		if artifactErr == nil {
+			artifact, artifactErr = common.StageArtifact(target, artifact, cfg)
+		}
+		if artifactErr != nil {
+			status = 1
+		}
 	case "syntax":
-		outputStr, status = common.SyntaxCheck(target, logfile, compileTarget, cfg)
+		outputStr, status = common.SyntaxCheck(buildTarget, logfile, compileTarget, cfg, timings)
+
+		if buildTarget != target {
+			common.CleanupDefinesWrapper(target, buildTarget)
+		}
 	default:
 		fmt.Println("Invalid mode:", mode)
-		return
+		return artifact, diagnostics, false
+	}
+
+	phase := time.Now()
+	diagnostics = common.ParseLogFile(outputStr, mode)
+
+	diagnostics, unusedSuppressions := common.ApplySuppressions(target, diagnostics)
+
+	// known warnings don't fail the build, new ones do
+	var newWarnings []common.Info
+	var baselineErr error
+	if cfg.Baseline != "" {
+		var baseline common.Baseline
+		baseline, baselineErr = common.LoadBaseline(cfg.Baseline)
+		if baselineErr == nil {
+			diagnostics, newWarnings = baseline.NewWarnings(diagnostics)
+		}
+	}
+
+	overrides, err := common.LoadSeverity(cfg)
+	if err != nil {
+		common.PrintError(err)
+		status = 1
+	}
+	diagnostics = common.ApplySeverity(diagnostics, overrides, cfg.Werror)
+
+	common.PrintResult(diagnostics, status, mode)
+	timings.Parse = time.Since(phase)
+	timings.MetaEditor = diagnostics.ElapsedTime()
+
+	phase = time.Now()
+	common.PrintDiagnostics(diagnostics, readFileCache, cfg)
+	common.PrintUnusedSuppressions(unusedSuppressions)
+
+	if fixes := common.SuggestFixes(target, diagnostics); cfg.Fix && len(fixes) > 0 {
+		applied, err := common.ApplyFixes(fixes)
+		if err != nil {
+			common.PrintError(err)
+		}
+		common.Logger.Info("Applied fixes", "Total", applied, "rebuild", "to check them")
+		fmt.Println()
+	} else {
+		common.PrintFixes(fixes)
+	}
+	timings.Render = time.Since(phase)
+	timings.Total = time.Since(start)
+	wineServer.Observe(*timings)
+
+	if err := common.SaveHistory(common.NewHistoryEntry(target, mode, diagnostics, cfg)); err != nil {
+		common.Logger.Warn("Failed to save the build history", "err", err)
+	}
+
+	if cfg.Timings {
+		common.PrintTimings(*timings)
+		if err := common.SaveTimings(*timings); err != nil {
+			common.PrintError(err)
+		}
+	}
+
+	if mode == "compile" && !diagnostics.HasErrors() {
+		fmt.Println()
+		if artifactErr != nil {
+			common.PrintError(artifactErr)
+		} else {
+			common.PrintArtifact(artifact)
+		}
+	}
+
+	succeeded := status == 0 && !diagnostics.HasErrors()
+
+	if cfg.Baseline != "" {
+		if baselineErr != nil {
+			common.PrintError(baselineErr)
+			succeeded = false
+		} else {
+			common.PrintBaselineWarnings(newWarnings, diagnostics, cfg.Baseline)
+			if len(newWarnings) > 0 {
+				succeeded = false
+			}
+		}
 	}
 
-	diagnostics := common.ParseLogFile(outputStr, status, mode)
+	if mode == "compile" && succeeded {
+		if cfg.Deploy {
+			if err := common.Deploy(target, artifact, cfg); err != nil {
+				common.PrintError(err)
+				succeeded = false
+			}
+		}
 
-	common.PrintDiagnostics(diagnostics, readFileCache)
+		if cfg.Test {
+			if _, err := common.RunTester(target, cfg); err != nil {
+				common.PrintError(err)
+				succeeded = false
+			}
+		}
+	}
 
 	if !cfg.PreserveLogs {
 		os.Remove(logfile)
@@ -41,6 +182,258 @@ func runBuild(mode string, target string, cfg *common.MQLConfig) {
 		fmt.Println()
 		fmt.Println("Logs are saved in", lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color(catppuccin.Latte.Lavender().Hex)).Render(logfile))
 	}
+
+	return artifact, diagnostics, succeeded
+}
+
+func runPackage(cfg *common.MQLConfig) bool {
+	if len(cfg.Args) == 0 {
+		common.PrintError(errors.New("Usage: go-mql-build package <target.mq4>..."))
+		return false
+	}
+
+	var artifacts []common.Artifact
+	for _, target := range cfg.Args {
+		artifact, _, ok := runBuild("compile", target, cfg)
+		if !ok {
+			common.PrintError(fmt.Errorf("Not packaging, %s failed to compile", target))
+			return false
+		}
+		artifacts = append(artifacts, artifact)
+	}
+
+	zipPath, err := common.Package(cfg.Args, artifacts, cfg)
+	if err != nil {
+		common.PrintError(err)
+		return false
+	}
+
+	fmt.Println()
+	common.Logger.Info("Packaged", "targets", len(cfg.Args), "zip", zipPath)
+	return true
+}
+
+func runVersion(cfg *common.MQLConfig) bool {
+	if len(cfg.Args) == 0 {
+		common.PrintError(errors.New("Usage: go-mql-build version <target.mq4> [major|minor|patch|x.yy]"))
+		return false
+	}
+
+	target := cfg.Args[0]
+
+	source, isUTF16, err := common.ReadSource(target)
+	if err != nil {
+		common.PrintError(err)
+		return false
+	}
+
+	version := common.ReadProperty(source, "version")
+	updated := source
+
+	if len(cfg.Args) > 1 {
+		newVersion, err := common.BumpVersion(version, cfg.Args[1])
+		if err != nil {
+			common.PrintError(err)
+			return false
+		}
+
+		common.Logger.Info("Version", "from", version, "to", newVersion)
+		version = newVersion
+		updated = common.WriteProperty(updated, "version", version)
+	}
+
+	if cfg.Description != "" {
+		updated = common.WriteProperty(updated, "description", cfg.Description)
+		common.Logger.Info("Description", "to", cfg.Description)
+	}
+
+	if cfg.BuildID {
+		buildID, err := common.GitShortHash(target)
+		if err != nil {
+			common.PrintError(err)
+			return false
+		}
+
+		updated = common.WriteBuildID(updated, buildID)
+		common.Logger.Info("Build ID", "to", buildID)
+	}
+
+	if len(cfg.Args) == 1 && cfg.Description == "" && !cfg.BuildID {
+		common.Logger.Info("Version", "target", target, "version", version)
+		return true
+	}
+
+	if err := common.WriteSource(target, updated, isUTF16); err != nil {
+		common.PrintError(err)
+		return false
+	}
+
+	return true
+}
+
+func runMatrix(cfg *common.MQLConfig) bool {
+	// every cell would deploy, test or fix on top of the previous one
+	if cfg.Deploy || cfg.Test || cfg.Fix {
+		common.PrintError(errors.New("The matrix only builds, -d, -t and --fix can't be combined with it"))
+		return false
+	}
+
+	matrixFile := common.DefaultMatrixFile
+	if len(cfg.Args) > 0 {
+		matrixFile = cfg.Args[0]
+	}
+
+	matrix, err := common.LoadMatrix(matrixFile, cfg)
+	if err != nil {
+		common.PrintError(err)
+		return false
+	}
+
+	if len(matrix.Targets) == 0 {
+		common.PrintError(fmt.Errorf("No targets in %s", matrixFile))
+		return false
+	}
+
+	allPassed := true
+
+	var results []common.MatrixResult
+	for _, target := range matrix.Targets {
+		for _, variant := range matrix.Variants {
+			for _, editor := range matrix.Editors {
+				cellCfg := *cfg
+				cellCfg.MetaEditorPath = editor.Path
+				cellCfg.Defines = variant.Defines
+				cellCfg.OutDir = common.MatrixOutDir(cfg, editor, variant)
+				cellCfg.PreserveLogs = true
+
+				_, _, passed := runBuild("compile", target, &cellCfg)
+				allPassed = allPassed && passed
+
+				results = append(results, common.MatrixResult{
+					Target:  target,
+					Variant: variant.Name,
+					Editor:  editor.Name,
+					Passed:  passed,
+					Logfile: common.OutputPath(target, ".log", &cellCfg),
+				})
+			}
+		}
+	}
+
+	common.PrintMatrixGrid(matrix, results)
+	return allPassed
+}
+
+// Compiles the targets and writes their warnings into the baseline
+func runBaseline(cfg *common.MQLConfig) bool {
+	if len(cfg.Args) == 0 {
+		common.PrintError(errors.New("Usage: go-mql-build baseline <target.mq4>..."))
+		return false
+	}
+
+	path := cfg.Baseline
+	if path == "" {
+		path = common.DefaultBaselineFile
+	}
+
+	// the snapshot has to take every warning, not only the new ones
+	buildCfg := *cfg
+	buildCfg.Baseline = ""
+	buildCfg.Werror = false
+
+	var baseline common.Baseline
+	for _, target := range cfg.Args {
+		_, diagnostics, _ := runBuild("compile", target, &buildCfg)
+		if diagnostics.HasErrors() {
+			common.PrintError(fmt.Errorf("Not writing the baseline, %s failed to compile", target))
+			return false
+		}
+		baseline.Add(diagnostics)
+	}
+
+	if err := baseline.Save(path); err != nil {
+		common.PrintError(err)
+		return false
+	}
+
+	fmt.Println()
+	common.Logger.Info("Baseline written", "targets", len(cfg.Args), "warnings", len(baseline.Warnings), "file", path)
+	return true
+}
+
+// Compiles the targets changed since the --changed ref
+func runChanged(cfg *common.MQLConfig) bool {
+	targets, err := common.ChangedTargets(cfg.Changed)
+	if err != nil {
+		common.PrintError(err)
+		return false
+	}
+
+	if len(targets) == 0 {
+		common.Logger.Info("Nothing changed", "since", cfg.Changed)
+		return true
+	}
+
+	common.Logger.Info("Changed", "since", cfg.Changed, "targets", len(targets))
+
+	var failed []string
+	for _, target := range targets {
+		if _, _, ok := runBuild("compile", target, cfg); !ok {
+			failed = append(failed, target)
+		}
+	}
+
+	fmt.Println()
+	if len(failed) > 0 {
+		common.PrintError(fmt.Errorf("%d of %d changed targets failed: %s", len(failed), len(targets), strings.Join(failed, ", ")))
+		return false
+	}
+
+	common.Logger.Info("Built", "targets", len(targets))
+	return true
+}
+
+func runExplain(cfg *common.MQLConfig) bool {
+	if len(cfg.Args) == 0 {
+		common.PrintError(errors.New("Usage: go-mql-build explain <code|message>"))
+		return false
+	}
+
+	query := strings.Join(cfg.Args, " ")
+
+	entries, err := common.LookupCatalog(query)
+	if err != nil {
+		common.PrintError(err)
+		return false
+	}
+
+	if len(entries) == 0 {
+		common.PrintError(fmt.Errorf("No explanation for %s, try a part of the message instead", query))
+		return false
+	}
+
+	for _, entry := range entries {
+		common.PrintCatalogEntry(entry)
+	}
+	fmt.Println()
+	return true
+}
+
+func runHistory(cfg *common.MQLConfig) bool {
+	entries, err := common.LoadHistory()
+	if err != nil {
+		common.PrintError(err)
+		return false
+	}
+
+	common.PrintHistory(entries, cfg.Args)
+	return true
+}
+
+// Exits with 1, stopping the wine server first since os.Exit skips the defers
+func exitFailed() {
+	wineServer.Stop()
+	os.Exit(1)
 }
 
 func main() {
@@ -59,14 +452,82 @@ func main() {
 	readFileCache = make(map[string][]string)
 
 	common.InitLogger()
+	common.InitWinePaths(cfg)
+
+	if cfg.WineServer {
+		wineServer = common.StartWineServer(cfg)
+		defer wineServer.Stop()
+
+		// stop it on ctrl+c too
+		interrupt := make(chan os.Signal, 1)
+		signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+		go func() {
+			<-interrupt
+			wineServer.Stop()
+			os.Exit(130)
+		}()
+	}
+
+	switch cfg.Command {
+	case "":
+	case "package":
+		if !runPackage(cfg) {
+			exitFailed()
+		}
+		return
+	case "version":
+		if !runVersion(cfg) {
+			exitFailed()
+		}
+		return
+	case "matrix":
+		if !runMatrix(cfg) {
+			exitFailed()
+		}
+		return
+	case "baseline":
+		if !runBaseline(cfg) {
+			exitFailed()
+		}
+		return
+	case "explain":
+		if !runExplain(cfg) {
+			exitFailed()
+		}
+		return
+	case "history":
+		if !runHistory(cfg) {
+			exitFailed()
+		}
+		return
+	case "doctor":
+		if !common.RunDoctor(cfg) {
+			exitFailed()
+		}
+		return
+	default:
+		common.PrintError(fmt.Errorf("Unknown command: %s", cfg.Command))
+		exitFailed()
+	}
+
+	if cfg.Changed != "" {
+		if !runChanged(cfg) {
+			exitFailed()
+		}
+		return
+	}
 
 	if cfg.Compile != "" {
-		runBuild("compile", cfg.Compile, cfg)
+		if _, _, ok := runBuild("compile", cfg.Compile, cfg); !ok {
+			exitFailed()
+		}
 		return
 	}
 
 	if cfg.Syntax != "" {
-		runBuild("syntax", cfg.Syntax, cfg)
+		if _, _, ok := runBuild("syntax", cfg.Syntax, cfg); !ok {
+			exitFailed()
+		}
 		return
 	}
 
@@ -77,6 +538,12 @@ func main() {
 		return
 	}
 
+	// the file picker needs an interactive terminal
+	if !cfg.Version && cfg.Compile == "" && cfg.Syntax == "" && !term.IsTerminal(int(os.Stdout.Fd())) {
+		flag.Usage()
+		return
+	}
+
 	if !cfg.Version && cfg.Compile == "" && cfg.Syntax == "" {
 
 		var filePicker common.FilePicker